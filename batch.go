@@ -0,0 +1,253 @@
+package schemer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+)
+
+// Batch streams store values encoded with a single schema in blocks of values
+// that are compressed independently of one another using a CompressionCodec. See
+// encoding-format.md for details.
+
+// BatchMagic is the first 4 bytes of a batch stream
+var BatchMagic = []byte("Smb\x01")
+
+// DefaultBatchSize is the default number of values in each block written by a
+// BatchWriter
+const DefaultBatchSize = 1000
+
+// DefaultMaxBlockSize is the default maximum size of a decompressed block read
+// by a BatchReader
+const DefaultMaxBlockSize = 64 << 20
+
+// BatchWriter writes blocks of values compressed with a CompressionCodec
+type BatchWriter struct {
+	w      io.Writer
+	codec  CompressionCodec
+	blocks blockWriter
+	out    bytes.Buffer
+}
+
+// NewBatchWriter writes the header of a batch stream to w and returns a
+// BatchWriter that writes values encoded with schema s and compressed with
+// the named codec (see RegisterCodec)
+func NewBatchWriter(w io.Writer, s Schema, codec string) (*BatchWriter, error) {
+	c, err := lookupCodec(codec)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := s.(Marshaler)
+	if !ok {
+		return nil, fmt.Errorf("schema does not implement MarshalSchemer")
+	}
+	schemaBytes, err := m.MarshalSchemer()
+	if err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(nil), BatchMagic...)
+	header = appendUvarint(header, uint64(len(codec)))
+	header = append(header, codec...)
+	header = append(header, schemaBytes...)
+	_, err = w.Write(header)
+	if err != nil {
+		return nil, err
+	}
+
+	bw := &BatchWriter{
+		w:     w,
+		codec: c,
+	}
+	bw.blocks = blockWriter{
+		plans:      planCache{schema: s},
+		maxBytes:   math.MaxInt,
+		maxValues:  DefaultBatchSize,
+		writeBlock: bw.writeBlock,
+	}
+	return bw, nil
+}
+
+// SetBatchSize sets the number of values in each block
+func (bw *BatchWriter) SetBatchSize(n int) {
+	bw.blocks.maxValues = n
+}
+
+// Schema returns the schema used to encode values
+func (bw *BatchWriter) Schema() Schema {
+	return bw.blocks.plans.schema
+}
+
+// Append encodes v and adds it to the current block. The block is compressed
+// and written once it is full.
+func (bw *BatchWriter) Append(v interface{}) error {
+	return bw.blocks.append(reflect.ValueOf(v), "BatchWriter")
+}
+
+// Flush compresses and writes the current block, if it is not empty
+func (bw *BatchWriter) Flush() error {
+	return bw.blocks.flush()
+}
+
+// writeBlock compresses and writes a block holding count encoded values
+func (bw *BatchWriter) writeBlock(count int, block []byte) error {
+	bw.out.Reset()
+	cw, err := bw.codec.NewWriter(&bw.out)
+	if err != nil {
+		return err
+	}
+	_, err = cw.Write(block)
+	if err != nil {
+		return err
+	}
+	err = cw.Close()
+	if err != nil {
+		return err
+	}
+
+	header := appendUvarint(nil, uint64(count))
+	header = appendUvarint(header, uint64(bw.out.Len()))
+	_, err = bw.w.Write(header)
+	if err != nil {
+		return err
+	}
+	_, err = bw.w.Write(bw.out.Bytes())
+	return err
+}
+
+// Close compresses and writes the current block. The underlying io.Writer is
+// not closed.
+func (bw *BatchWriter) Close() error {
+	return bw.blocks.close()
+}
+
+// BatchReader reads values from a batch stream written by a BatchWriter. It
+// is used in the same way as a FileReader.
+type BatchReader struct {
+	r            decodeReader
+	codecName    string
+	codec        CompressionCodec
+	blocks       blockReader
+	block        bytes.Buffer // decompressed block
+	maxBlockSize int
+}
+
+// NewBatchReader reads the header of a batch stream from r and returns a
+// BatchReader for the stream. An error is returned if the codec used to write
+// the stream is not registered. If r does not implement io.ByteReader, it is
+// buffered.
+func NewBatchReader(r io.Reader) (*BatchReader, error) {
+	dr, ok := r.(decodeReader)
+	if !ok {
+		dr = bufio.NewReader(r)
+	}
+
+	magic := make([]byte, len(BatchMagic))
+	_, err := io.ReadFull(dr, magic)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, BatchMagic) {
+		return nil, fmt.Errorf("not a batch stream")
+	}
+
+	name, err := readBytes(dr)
+	if err != nil {
+		return nil, err
+	}
+	c, err := lookupCodec(string(name))
+	if err != nil {
+		return nil, err
+	}
+	s, err := DecodeSchema(dr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch schema: %w", err)
+	}
+
+	br := &BatchReader{
+		r:            dr,
+		codecName:    string(name),
+		codec:        c,
+		maxBlockSize: DefaultMaxBlockSize,
+	}
+	br.blocks = blockReader{
+		plans:     planCache{schema: s},
+		readBlock: br.readBlock,
+	}
+	return br, nil
+}
+
+// SetMaxBlockSize sets the maximum size of a decompressed block. Reading a
+// block that decompresses to more than n bytes fails, which protects against
+// small blocks that decompress to a huge amount of data.
+func (br *BatchReader) SetMaxBlockSize(n int) {
+	br.maxBlockSize = n
+}
+
+// Schema returns the schema used to encode the values in the stream (i.e. the
+// writer schema)
+func (br *BatchReader) Schema() Schema {
+	return br.blocks.plans.schema
+}
+
+// Codec returns the name of the codec used to compress the stream
+func (br *BatchReader) Codec() string {
+	return br.codecName
+}
+
+// Next prepares the next value for reading with Decode. It returns false when
+// there are no more values or an error occurs; use Err to check for errors.
+func (br *BatchReader) Next() bool {
+	return br.blocks.next()
+}
+
+// readBlock reads and decompresses the next block of the stream
+func (br *BatchReader) readBlock() (uint64, []byte, error) {
+	count, err := ReadUvarint(br.r)
+	if err != nil {
+		// io.EOF indicates the end of the stream
+		return 0, nil, err
+	}
+	compressed, err := readBytes(br.r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	cr, err := br.codec.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return 0, nil, err
+	}
+	br.block.Reset()
+	// Read one more byte than allowed to detect blocks that are too large
+	_, err = br.block.ReadFrom(io.LimitReader(cr, int64(br.maxBlockSize)+1))
+	if closeErr := cr.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("cannot decompress block: %w", err)
+	}
+	if br.block.Len() > br.maxBlockSize {
+		return 0, nil, fmt.Errorf("decompressed block size exceeds maximum of %d", br.maxBlockSize)
+	}
+	return count, br.block.Bytes(), nil
+}
+
+// Decode reads the next value from the stream and stores it in i, which must
+// be a non-nil pointer. io.EOF is returned if there are no more values.
+func (br *BatchReader) Decode(i interface{}) error {
+	return br.DecodeValue(reflect.ValueOf(i))
+}
+
+// DecodeValue reads the next value from the stream and stores it in v, which
+// must be a non-nil pointer. io.EOF is returned if there are no more values.
+func (br *BatchReader) DecodeValue(v reflect.Value) error {
+	return br.blocks.decodeValue(v)
+}
+
+// Err returns the first error encountered by Next, other than io.EOF
+func (br *BatchReader) Err() error {
+	return br.blocks.errValue()
+}
@@ -0,0 +1,157 @@
+package schemer
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// xorWriter and xorReader implement a trivial codec used to test RegisterCodec
+type xorWriter struct {
+	w io.Writer
+}
+
+func (x xorWriter) Write(p []byte) (int, error) {
+	b := make([]byte, len(p))
+	for i := range p {
+		b[i] = p[i] ^ 0xFF
+	}
+	return x.w.Write(b)
+}
+
+func (x xorWriter) Close() error {
+	return nil
+}
+
+type xorReader struct {
+	r io.Reader
+}
+
+func (x xorReader) Read(p []byte) (int, error) {
+	n, err := x.r.Read(p)
+	for i := 0; i < n; i++ {
+		p[i] ^= 0xFF
+	}
+	return n, err
+}
+
+func TestBatch(t *testing.T) {
+
+	// Note: the codec is still registered if the test is run several times
+	_, err := lookupCodec("xor")
+	if err != nil {
+		err = RegisterCodec("xor", CompressionCodec{
+			NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+				return xorWriter{w}, nil
+			},
+			NewReader: func(r io.Reader) (io.ReadCloser, error) {
+				return io.NopCloser(xorReader{r}), nil
+			},
+		})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = RegisterCodec("gzip", CompressionCodec{}); err == nil {
+		t.Error("expected error registering duplicate codec")
+	}
+
+	s, err := SchemaOf(testRecord{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 250
+	var sizes = make(map[string]int)
+	for _, codec := range Codecs() {
+		var buf bytes.Buffer
+		bw, err := NewBatchWriter(&buf, s, codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bw.SetBatchSize(100)
+		for i := 0; i < n; i++ {
+			err = bw.Append(testRecord{ID: i, Name: "repeated name", Tags: []string{"a"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = bw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		sizes[codec] = buf.Len()
+
+		br, err := NewBatchReader(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if br.Codec() != codec {
+			t.Errorf("expected codec %q; got %q", codec, br.Codec())
+		}
+		count := 0
+		for br.Next() {
+			var v testRecord
+			if err = br.Decode(&v); err != nil {
+				t.Fatalf("%s: %v", codec, err)
+			}
+			if v.ID != count || v.Name != "repeated name" {
+				t.Fatalf("%s: unexpected value %v", codec, v)
+			}
+			count++
+		}
+		if err = br.Err(); err != nil {
+			t.Fatalf("%s: %v", codec, err)
+		}
+		if count != n {
+			t.Errorf("%s: expected %d values; got %d", codec, n, count)
+		}
+	}
+
+	for _, codec := range []string{"flate", "gzip", "zlib", "lzw"} {
+		if sizes[codec] >= sizes["none"] {
+			t.Errorf("%s: expected compressed size %d < %d", codec, sizes[codec], sizes["none"])
+		}
+	}
+
+	if _, err = NewBatchWriter(io.Discard, s, "unknown"); err == nil {
+		t.Error("expected error for unknown codec")
+	}
+}
+
+func TestBatchMaxBlockSize(t *testing.T) {
+	s := &VarStringSchema{}
+	var buf bytes.Buffer
+	bw, err := NewBatchWriter(&buf, s, "gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a block of highly compressible data
+	if err = bw.Append(strings.Repeat("x", 1<<20)); err != nil {
+		t.Fatal(err)
+	}
+	if err = bw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	br, err := NewBatchReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	br.SetMaxBlockSize(1 << 16)
+	if br.Next() {
+		t.Fatal("expected block to exceed maximum size")
+	}
+	if err = br.Err(); err == nil || !strings.Contains(err.Error(), "exceeds maximum") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	br, err = NewBatchReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var str string
+	if !br.Next() || br.Decode(&str) != nil || len(str) != 1<<20 {
+		t.Errorf("unexpected value of length %d, %v", len(str), br.Err())
+	}
+}
@@ -2,7 +2,6 @@ package schemer
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"reflect"
@@ -21,38 +20,33 @@ func (s *BoolSchema) Encode(w io.Writer, i interface{}) error {
 // EncodeValue uses the schema to write the encoded value of v to the output
 // stream
 func (s *BoolSchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
 
-	done, err := PreEncode(w, &v, s.Nullable())
-	if err != nil || done {
-		return err
-	}
+// AppendEncode appends the encoded value of i to dst and returns the extended
+// slice
+func (s *BoolSchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
 
-	t := v.Type()
-	k := t.Kind()
+// AppendEncodeValue appends the encoded value of v to dst and returns the
+// extended slice
+func (s *BoolSchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
 
-	if k != reflect.Bool {
-		return fmt.Errorf("BoolSchema only supports encoding boolean values")
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
+	if err != nil || done {
+		return dst, err
 	}
 
-	var boolToEncode byte
+	if v.Kind() != reflect.Bool {
+		return dst, fmt.Errorf("BoolSchema only supports encoding boolean values")
+	}
 
 	if v.Bool() {
 		// we are trying to encode a true value
-		boolToEncode = 1
-	}
-
-	switch k {
-	case reflect.Bool:
-		n, err := w.Write([]byte{boolToEncode})
-		if err == nil && n != 1 {
-			return errors.New("unexpected number of bytes written")
-		}
-
-	default:
-		return errors.New("can only encode boolean types when using BoolSchema")
+		return append(dst, 1), nil
 	}
-
-	return nil
+	return append(dst, 0), nil
 }
 
 // Decode uses the schema to read the next encoded value from the input
@@ -64,6 +58,12 @@ func (s *BoolSchema) Decode(r io.Reader, i interface{}) error {
 	return s.DecodeValue(r, reflect.ValueOf(i))
 }
 
+// DecodeBytes decodes the encoded value at the start of data, stores it in
+// i, and returns the number of bytes read
+func (s *BoolSchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
 // DecodeValue uses the schema to read the next encoded value from the input
 // stream and stores it in v
 func (s *BoolSchema) DecodeValue(r io.Reader, v reflect.Value) error {
@@ -0,0 +1,195 @@
+package schemer
+
+import (
+	"encoding/binary"
+	"io"
+	"reflect"
+	"unsafe"
+)
+
+// nativeLittleEndian is true if the host stores numbers in little-endian
+// order, which is the byte order of encoded fixed-width numbers
+var nativeLittleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// bulkLayout describes how the elements of an array are encoded in bulk
+type bulkLayout struct {
+	size   int  // size of each element in bytes
+	word   int  // size of each number in an element (differs for complex numbers)
+	zigzag bool // signed integers are zig-zag encoded
+}
+
+// bulkLayoutOf checks whether the elements of an array with element schema s
+// and Go element type t can be encoded and decoded in bulk (i.e. by copying
+// the memory of the whole array). This is the case when s is a non-nullable
+// FloatSchema, FixedIntSchema, or ComplexSchema of the same size as t.
+func bulkLayoutOf(s Schema, t reflect.Type) (bulkLayout, bool) {
+	k := t.Kind()
+	bits := 0
+	words := 1
+	zigzag := false
+	switch s := s.(type) {
+	case *FloatSchema:
+		if !s.Nullable() && (k == reflect.Float32 || k == reflect.Float64) {
+			bits = s.Bits
+		}
+	case *FixedIntSchema:
+		if !s.Nullable() && (s.Signed && isIntKind(k) || !s.Signed && isUintKind(k)) {
+			bits = s.Bits
+			zigzag = s.Signed
+		}
+	case *ComplexSchema:
+		if !s.Nullable() && (k == reflect.Complex64 || k == reflect.Complex128) {
+			bits = s.Bits
+			words = 2
+		}
+	}
+	if bits == 0 || int(t.Size())*8 != bits {
+		return bulkLayout{}, false
+	}
+	return bulkLayout{size: bits / 8, word: bits / 8 / words, zigzag: zigzag}, true
+}
+
+// bulkBytes returns the memory of the elements of slice or array v, which must
+// be addressable if it is an array
+func bulkBytes(v reflect.Value, size int) []byte {
+	n := v.Len() * size
+	if n == 0 {
+		return nil
+	}
+	var p unsafe.Pointer
+	if v.Kind() == reflect.Slice {
+		p = v.UnsafePointer()
+	} else {
+		p = v.Addr().UnsafePointer()
+	}
+	return unsafe.Slice((*byte)(p), n)
+}
+
+// swapWords reverses the byte order of each word in b
+func swapWords(b []byte, word int) {
+	for i := 0; i+word <= len(b); i += word {
+		w := b[i : i+word]
+		for j, k := 0, word-1; j < k; j, k = j+1, k-1 {
+			w[j], w[k] = w[k], w[j]
+		}
+	}
+}
+
+// getWord returns the little-endian word at the start of b
+func getWord(b []byte, word int) uint64 {
+	switch word {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(b))
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(b))
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+// putWord stores x at the start of b as a little-endian word
+func putWord(b []byte, word int, x uint64) {
+	switch word {
+	case 1:
+		b[0] = byte(x)
+	case 2:
+		binary.LittleEndian.PutUint16(b, uint16(x))
+	case 4:
+		binary.LittleEndian.PutUint32(b, uint32(x))
+	default:
+		binary.LittleEndian.PutUint64(b, x)
+	}
+}
+
+// zigzagWords zig-zag encodes each signed little-endian word in b
+func zigzagWords(b []byte, word int) {
+	shift := 64 - 8*uint(word)
+	for i := 0; i+word <= len(b); i += word {
+		// sign-extend the word
+		x := int64(getWord(b[i:], word)<<shift) >> shift
+		putWord(b[i:], word, uint64(x<<1)^uint64(x>>63))
+	}
+}
+
+// unzigzagWords decodes each zig-zag encoded little-endian word in b
+func unzigzagWords(b []byte, word int) {
+	for i := 0; i+word <= len(b); i += word {
+		u := getWord(b[i:], word)
+		putWord(b[i:], word, (u>>1)^-(u&1))
+	}
+}
+
+// appendBulk appends the encoded elements of slice or array v to dst (see
+// bulkLayoutOf)
+func appendBulk(dst []byte, v reflect.Value, l bulkLayout) []byte {
+	if v.Kind() == reflect.Array && !v.CanAddr() {
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+	start := len(dst)
+	dst = append(dst, bulkBytes(v, l.size)...)
+	if !nativeLittleEndian {
+		swapWords(dst[start:], l.word)
+	}
+	if l.zigzag {
+		zigzagWords(dst[start:], l.word)
+	}
+	return dst
+}
+
+// readBulk reads the encoded elements of slice or array v from r directly
+// into the memory of v (see bulkLayoutOf). Arrays must be addressable.
+func readBulk(r io.Reader, v reflect.Value, l bulkLayout) error {
+	b := bulkBytes(v, l.size)
+	if len(b) == 0 {
+		return nil
+	}
+	if _, err := io.ReadFull(r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if l.zigzag {
+		unzigzagWords(b, l.word)
+	}
+	if !nativeLittleEndian {
+		swapWords(b, l.word)
+	}
+	return nil
+}
+
+// readBulkSlice returns a new slice of type t with n elements, which are read
+// from r in bulk (see readBulk). If the length cannot be checked against the
+// remaining input (see checkLen), the elements are read in chunks, so that
+// memory is only allocated for elements that are present in the input.
+func readBulkSlice(r io.Reader, t reflect.Type, n uint64, l bulkLayout) (reflect.Value, error) {
+	checked, err := checkLen(r, n, uint64(l.size))
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	chunk := preallocLen(t)
+	if checked || n <= uint64(chunk) {
+		v := reflect.MakeSlice(t, int(n), int(n))
+		return v, readBulk(r, v, l)
+	}
+
+	v := reflect.MakeSlice(t, 0, chunk)
+	for uint64(v.Len()) < n {
+		m := chunk
+		if left := n - uint64(v.Len()); left < uint64(m) {
+			m = int(left)
+		}
+		start := v.Len()
+		v = reflect.AppendSlice(v, reflect.MakeSlice(t, m, m))
+		if err := readBulk(r, v.Slice(start, v.Len()), l); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,199 @@
+package schemer
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// appendElements is the per-element encoding of an array, which bulk encoding
+// must match
+func appendElements(dst []byte, elem Schema, v reflect.Value) []byte {
+	for i := 0; i < v.Len(); i++ {
+		var err error
+		dst, err = appendEncodeValue(elem, dst, v.Index(i))
+		if err != nil {
+			panic(err)
+		}
+	}
+	return dst
+}
+
+func TestBulkArrays(t *testing.T) {
+
+	f32 := &FloatSchema{Bits: 32}
+	f64 := &FloatSchema{Bits: 64}
+	i32 := &FixedIntSchema{Signed: true, Bits: 32}
+	u16 := &FixedIntSchema{Bits: 16}
+	i8 := &FixedIntSchema{Signed: true, Bits: 8}
+	c64 := &ComplexSchema{Bits: 64}
+	c128 := &ComplexSchema{Bits: 128}
+
+	tests := []struct {
+		s     Schema
+		value interface{}
+		bulk  bool
+	}{
+		{&VarArraySchema{Element: f64}, []float64{1.5, -2, 1e300}, true},
+		{&VarArraySchema{Element: f32}, []float32{0.25, -3}, true},
+		{&VarArraySchema{Element: i32}, []int32{-1, 2, 1 << 30, -1 << 31}, true},
+		{&VarArraySchema{Element: u16}, []uint16{0xFFFF, 1}, true},
+		{&VarArraySchema{Element: c64}, []complex64{1 + 2i, -3i}, true},
+		{&VarArraySchema{Element: f64}, []float64{}, true},
+		{&FixedArraySchema{Length: 3, Element: f32}, [3]float32{1, 2, 3}, true},
+		{&FixedArraySchema{Length: 2, Element: c128}, [2]complex128{1 + 1i, 2 - 2i}, true},
+		{&FixedArraySchema{Length: 4, Element: i8}, [4]int8{-128, 0, 1, 127}, true},
+		// elements that do not match the schema's size are encoded per element
+		{&VarArraySchema{Element: f64}, []float32{1, 2}, false},
+		{&VarArraySchema{Element: &FloatSchema{SchemaOptions{nullable: true}, 64}}, []float64{1}, false},
+	}
+
+	for _, test := range tests {
+		v := reflect.ValueOf(test.value)
+		var elem Schema
+		var expected []byte
+		switch s := test.s.(type) {
+		case *VarArraySchema:
+			elem = s.Element
+			expected = appendUvarint(nil, uint64(v.Len()))
+		case *FixedArraySchema:
+			elem = s.Element
+		}
+		expected = appendElements(expected, elem, v)
+
+		if _, ok := bulkLayoutOf(elem, v.Type().Elem()); ok != test.bulk {
+			t.Errorf("%T: expected bulk encoding to be %v", test.value, test.bulk)
+		}
+
+		p, err := Compile(test.s, v.Type())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range []Schema{test.s, p} {
+			var buf bytes.Buffer
+			if err := s.Encode(&buf, test.value); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), expected) {
+				t.Errorf("%T %T: expected %v; got %v", s, test.value, expected, buf.Bytes())
+			}
+
+			decoded := reflect.New(v.Type())
+			if err := s.Decode(&buf, decoded.Interface()); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded.Elem().Interface(), test.value) {
+				t.Errorf("%T: expected %v; got %v", s, test.value, decoded.Elem())
+			}
+
+			// truncated input
+			if len(expected) > 1 {
+				decoded = reflect.New(v.Type())
+				_, err = s.(BytesDecoder).DecodeBytes(expected[:len(expected)-1], decoded.Interface())
+				if err != io.ErrUnexpectedEOF {
+					t.Errorf("%T %T: expected io.ErrUnexpectedEOF; got %v", s, test.value, err)
+				}
+			}
+		}
+	}
+
+	// slices are not allocated for lengths that exceed the input
+	var floats []float64
+	data := appendUvarint(nil, 1<<40)
+	_, err := (&VarArraySchema{Element: f64}).DecodeBytes(data, &floats)
+	if err != io.ErrUnexpectedEOF || floats != nil {
+		t.Errorf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+}
+
+func TestBulkWords(t *testing.T) {
+	b := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	swapWords(b, 4)
+	expected := []byte{4, 3, 2, 1, 8, 7, 6, 5}
+	if !bytes.Equal(b, expected) {
+		t.Errorf("expected %v; got %v", expected, b)
+	}
+
+	// -1, 1, -32768, and 32767 as little-endian int16 values
+	b = []byte{0xFF, 0xFF, 1, 0, 0, 0x80, 0xFF, 0x7F}
+	zigzagWords(b, 2)
+	expected = []byte{1, 0, 2, 0, 0xFF, 0xFF, 0xFE, 0xFF}
+	if !bytes.Equal(b, expected) {
+		t.Errorf("expected %v; got %v", expected, b)
+	}
+	unzigzagWords(b, 2)
+	expected = []byte{0xFF, 0xFF, 1, 0, 0, 0x80, 0xFF, 0x7F}
+	if !bytes.Equal(b, expected) {
+		t.Errorf("expected %v; got %v", expected, b)
+	}
+}
+
+func benchmarkFloats() []float64 {
+	floats := make([]float64, 1024)
+	for i := range floats {
+		floats[i] = float64(i) / 3
+	}
+	return floats
+}
+
+// BenchmarkBulkEncode compares bulk encoding of a []float64 with encoding
+// each element separately
+func BenchmarkBulkEncode(b *testing.B) {
+	floats := benchmarkFloats()
+	s := &VarArraySchema{Element: &FloatSchema{Bits: 64}}
+	v := reflect.ValueOf(floats)
+	buf := make([]byte, 0, 16*1024)
+
+	b.Run("bulk", func(b *testing.B) {
+		b.SetBytes(int64(len(floats) * 8))
+		for i := 0; i < b.N; i++ {
+			if _, err := s.AppendEncodeValue(buf[:0], v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("element", func(b *testing.B) {
+		b.SetBytes(int64(len(floats) * 8))
+		for i := 0; i < b.N; i++ {
+			dst := appendUvarint(buf[:0], uint64(v.Len()))
+			appendElements(dst, s.Element, v)
+		}
+	})
+}
+
+// BenchmarkBulkDecode compares bulk decoding of a []float64 with decoding
+// each element separately
+func BenchmarkBulkDecode(b *testing.B) {
+	floats := benchmarkFloats()
+	s := &VarArraySchema{Element: &FloatSchema{Bits: 64}}
+	data, err := s.AppendEncode(nil, floats)
+	if err != nil {
+		b.Fatal(err)
+	}
+	decoded := make([]float64, len(floats))
+	v := reflect.ValueOf(decoded)
+
+	b.Run("bulk", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if _, err := s.DecodeBytes(data, &decoded); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("element", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			r := sliceReader{data: data}
+			if _, err := ReadUvarint(&r); err != nil {
+				b.Fatal(err)
+			}
+			for j := 0; j < v.Len(); j++ {
+				if err := s.Element.DecodeValue(&r, v.Index(j)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
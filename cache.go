@@ -0,0 +1,161 @@
+package schemer
+
+import (
+	"reflect"
+	"sync"
+)
+
+// SchemaOfType returns a Schema for the specified Go type.
+// Schemas are cached by type, so the registered schema generators and the
+// built-in logic are only run once per type. The cache is cleared whenever a
+// schema generator is registered or unregistered, or when ClearCache is
+// called. The returned Schema is shared with other callers and must not be
+// modified; use CloneSchema to obtain a copy that may be modified.
+func (reg *Registry) SchemaOfType(t reflect.Type) (Schema, error) {
+	gens := reg.generators()
+
+	if s, ok := gens.cache.Load(t); ok {
+		return s.(Schema), nil
+	}
+
+	s, err := reg.schemaOfType(gens, t)
+	if err != nil || s == nil {
+		return s, err
+	}
+
+	// Note: if the schema generators changed while building s, gens.cache is
+	// no longer used by the Registry and storing s is harmless
+	gens.cache.Store(t, s)
+	return s, nil
+}
+
+// ClearCache removes all schemas from the registry's schema cache. It need
+// only be called if a registered schema generator would now return a
+// different Schema for a type it has already been called with.
+func (reg *Registry) ClearCache() {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	reg.rebuild()
+}
+
+// CloneSchema returns a copy of s that may be modified without affecting s.
+// The built-in schemas are copied deeply, including the child schemas of
+// array and object schemas. Other schemas that are pointers to structs (i.e.
+// schemas of custom types) are copied shallowly, so a custom schema that
+// holds pointers, slices, or maps shares them with the copy. Other schemas
+// are returned as-is.
+func CloneSchema(s Schema) Schema {
+	if s == nil {
+		return nil
+	}
+
+	switch s := s.(type) {
+	case *FixedArraySchema:
+		c := *s
+		c.Element = CloneSchema(s.Element)
+		return &c
+
+	case *VarArraySchema:
+		c := *s
+		c.Element = CloneSchema(s.Element)
+		return &c
+
+	case *VarObjectSchema:
+		c := *s
+		c.Key = CloneSchema(s.Key)
+		c.Value = CloneSchema(s.Value)
+		return &c
+
+	case *FixedObjectSchema:
+		c := *s
+		if s.Fields == nil {
+			return &c
+		}
+		c.Fields = make([]ObjectField, len(s.Fields))
+		for i, f := range s.Fields {
+			c.Fields[i] = ObjectField{
+				Aliases: append([]string(nil), f.Aliases...),
+				Schema:  CloneSchema(f.Schema),
+				Default: f.Default,
+			}
+		}
+		return &c
+
+	case *EnumSchema:
+		c := *s
+		if s.Values != nil {
+			c.Values = make(map[int]string, len(s.Values))
+			for k, v := range s.Values {
+				c.Values[k] = v
+			}
+		}
+		return &c
+	}
+
+	// shallow copy of all other schemas (including schemas of custom types)
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return s
+	}
+	c := reflect.New(v.Elem().Type())
+	c.Elem().Set(v.Elem())
+	if cs, ok := c.Interface().(Schema); ok {
+		return cs
+	}
+	return s
+}
+
+// structFieldsCache maps struct types to their []structField
+var structFieldsCache sync.Map
+
+// structField holds the parsed struct tag of a struct field and its default
+// value, so that they need not be computed each time a value is decoded
+type structField struct {
+	tag    StructTag
+	def    reflect.Value // default value converted to the field's type
+	defErr error         // error converting the default value
+}
+
+// setDefault stores the default value of the field in v, which must be of the
+// field's type
+func (f *structField) setDefault(v reflect.Value) error {
+	if f.defErr != nil {
+		return f.defErr
+	}
+	v.Set(copyDefault(f.def))
+	return nil
+}
+
+// structFieldsOf returns the parsed struct tags and default values of the
+// fields of struct type t. The returned slice must not be modified.
+func structFieldsOf(t reflect.Type) []structField {
+	if fields, ok := structFieldsCache.Load(t); ok {
+		return fields.([]structField)
+	}
+
+	fields := make([]structField, t.NumField())
+	for i := range fields {
+		f := t.Field(i)
+		fields[i].tag = ParseStructTag(f.Tag.Get(StructTagName))
+		if !fields[i].tag.DefaultSet || len(f.PkgPath) != 0 {
+			continue
+		}
+		fields[i].def = reflect.New(f.Type).Elem()
+		fields[i].defErr = setDefault(fields[i].def, fields[i].tag.Default)
+	}
+
+	structFieldsCache.Store(t, fields)
+	return fields
+}
+
+// copyDefault returns a copy of default value def that does not share
+// pointers with def
+func copyDefault(def reflect.Value) reflect.Value {
+	if def.Kind() != reflect.Ptr || def.IsNil() {
+		return def
+	}
+	c := reflect.New(def.Type().Elem())
+	c.Elem().Set(copyDefault(def.Elem()))
+	return c
+}
@@ -0,0 +1,171 @@
+package schemer
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// countingGenerator counts the number of times SchemaOfType is called
+type countingGenerator struct {
+	calls int
+}
+
+func (sg *countingGenerator) SchemaOfType(t reflect.Type) (Schema, error) {
+	sg.calls++
+	return nil, nil
+}
+
+func (sg *countingGenerator) DecodeSchema(r io.Reader) (Schema, error) {
+	return nil, nil
+}
+
+func (sg *countingGenerator) DecodeSchemaJSON(r io.Reader) (Schema, error) {
+	return nil, nil
+}
+
+func TestSchemaCache(t *testing.T) {
+
+	reg := NewRegistry()
+	sg := &countingGenerator{}
+	if err := reg.Register(sg); err != nil {
+		t.Fatal(err)
+	}
+
+	// sourceStruct has a number of fields, each of which calls SchemaOfType
+	_, err := reg.SchemaOf(sourceStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := sg.calls
+	if calls == 0 {
+		t.Fatal("expected generator to be called")
+	}
+
+	_, err = reg.SchemaOf(&sourceStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sg.calls != calls {
+		t.Errorf("expected cached schema; generator called %d times", sg.calls-calls)
+	}
+
+	// ClearCache causes generators to be called again
+	reg.ClearCache()
+	_, err = reg.SchemaOf(sourceStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sg.calls != 2*calls {
+		t.Errorf("expected %d calls after ClearCache; got %d", 2*calls, sg.calls)
+	}
+
+	// Register clears the cache
+	if err = reg.Register(&fixedStringGenerator{length: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if stringSchemaLength(t, reg) != 3 {
+		t.Error("expected cache to be cleared by Register")
+	}
+	reg.Unregister(sg)
+	calls = sg.calls
+	_, err = reg.SchemaOf(sourceStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sg.calls != calls {
+		t.Error("unregistered generator was called")
+	}
+}
+
+// make sure that modifying a copy of a returned schema does not modify cached
+// schemas
+func TestSchemaCacheClone(t *testing.T) {
+
+	reg := NewRegistry()
+
+	tests := []interface{}{
+		sourceStruct{},
+		[]*int{},
+		[3]string{},
+		map[string]float64{},
+		time.Time{},
+	}
+
+	for _, test := range tests {
+		s1, err := reg.SchemaOf(test)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := s1.(Marshaler).MarshalSchemer()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s2, err := reg.SchemaOf(test)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s1 != s2 {
+			t.Fatalf("%T: expected the cached schema", test)
+		}
+
+		s2 = CloneSchema(s2)
+		if s1 == s2 {
+			t.Fatalf("%T: expected a copy of the cached schema", test)
+		}
+		s2.(interface{ SetNullable(bool) }).SetNullable(true)
+		switch s := s2.(type) {
+		case *FixedObjectSchema:
+			s.Fields[0].Aliases[0] = "modified"
+			s.Fields[0].Schema.(interface{ SetNullable(bool) }).SetNullable(true)
+		case *VarArraySchema:
+			s.Element.(interface{ SetNullable(bool) }).SetNullable(false)
+		case *FixedArraySchema:
+			s.Element.(interface{ SetNullable(bool) }).SetNullable(true)
+		case *VarObjectSchema:
+			s.Value.(interface{ SetNullable(bool) }).SetNullable(true)
+		}
+
+		s3, err := reg.SchemaOf(test)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := s3.(Marshaler).MarshalSchemer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("%T: cached schema was modified", test)
+		}
+	}
+}
+
+// struct tags that set schema options must not modify the cached schemas of
+// the field types
+func TestSchemaCacheTags(t *testing.T) {
+
+	type tagged struct {
+		A int `schemer:",null"`
+		B int
+	}
+
+	reg := NewRegistry()
+	s, err := reg.SchemaOf(tagged{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := s.(*FixedObjectSchema).Fields
+	if !fields[0].Schema.(*VarIntSchema).Nullable() ||
+		fields[1].Schema.(*VarIntSchema).Nullable() {
+		t.Error("expected only field A to be nullable")
+	}
+	is, err := reg.SchemaOf(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if is.(*VarIntSchema).Nullable() {
+		t.Error("cached int schema was modified by struct tag")
+	}
+}
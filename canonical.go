@@ -0,0 +1,95 @@
+package schemer
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+)
+
+// CanonicalForm returns the canonical binary encoding of s. The canonical form
+// is the encoding returned by MarshalSchemer with the following changes:
+//   - The aliases of each object field are sorted. Object fields themselves
+//     are not reordered, since their order determines how values are encoded.
+//   - Enumerated values are written in ascending order.
+//   - Options that do not affect the encoded values (i.e. weak decoding and
+//     default values of object fields) are not included.
+//
+// Equivalent schemas have the same canonical form regardless of whether they
+// were built by SchemaOf, DecodeSchema, or DecodeSchemaJSON.
+//
+// CanonicalForm, Fingerprint64, and FingerprintSHA256 are functions rather
+// than methods so that they work with every Schema, including schemas of
+// custom types, without adding methods to the Schema interface.
+func CanonicalForm(s Schema) ([]byte, error) {
+	if s == nil {
+		return nil, fmt.Errorf("cannot get canonical form of nil schema")
+	}
+
+	c := CloneSchema(s)
+	canonicalize(c)
+
+	m, ok := c.(Marshaler)
+	if !ok {
+		return nil, fmt.Errorf("schema does not implement MarshalSchemer")
+	}
+	return m.MarshalSchemer()
+}
+
+// canonicalize sorts the aliases of all object fields in s. s must not be
+// shared (see CloneSchema).
+func canonicalize(s Schema) {
+	switch s := s.(type) {
+	case *FixedArraySchema:
+		canonicalize(s.Element)
+	case *VarArraySchema:
+		canonicalize(s.Element)
+	case *VarObjectSchema:
+		canonicalize(s.Key)
+		canonicalize(s.Value)
+	case *FixedObjectSchema:
+		for _, f := range s.Fields {
+			sort.Strings(f.Aliases)
+			canonicalize(f.Schema)
+		}
+	}
+}
+
+// Fingerprint64 returns the 64-bit Rabin fingerprint (CRC-64-AVRO) of the
+// canonical form of s. Fingerprints are suitable for identifying schemas in
+// caches and registries.
+func Fingerprint64(s Schema) (uint64, error) {
+	b, err := CanonicalForm(s)
+	if err != nil {
+		return 0, err
+	}
+
+	fp := uint64(rabinEmpty)
+	for _, c := range b {
+		fp = (fp >> 8) ^ rabinTable[byte(fp)^c]
+	}
+	return fp, nil
+}
+
+// FingerprintSHA256 returns the SHA-256 hash of the canonical form of s
+func FingerprintSHA256(s Schema) ([sha256.Size]byte, error) {
+	b, err := CanonicalForm(s)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(b), nil
+}
+
+// rabinEmpty is the fingerprint of an empty byte slice and the polynomial used
+// to build rabinTable
+const rabinEmpty = 0xc15d213aa4d7a795
+
+var rabinTable = func() (table [256]uint64) {
+	for i := range table {
+		fp := uint64(i)
+		for j := 0; j < 8; j++ {
+			fp = (fp >> 1) ^ (rabinEmpty & -(fp & 1))
+		}
+		table[i] = fp
+	}
+	return
+}()
@@ -0,0 +1,131 @@
+package schemer
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type fingerprintStruct struct {
+	ID      uint64
+	Name    string `schemer:"[Name,FullName]"`
+	Color   int
+	Tags    []string
+	Scores  map[string]float32
+	Created time.Time
+	Flag    *bool `schemer:",weak"`
+	Inner   struct {
+		A [4]int8
+		B complex64
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+
+	s, err := SchemaOf(fingerprintStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[int]string)
+	for i, name := range []string{"red", "green", "blue", "cyan", "magenta", "yellow"} {
+		values[i] = name
+	}
+	s = CloneSchema(s)
+	s.(*FixedObjectSchema).Fields[2].Schema = &EnumSchema{Values: values}
+
+	b, err := s.(Marshaler).MarshalSchemer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromBinary, err := DecodeSchema(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := DecodeSchemaJSON(bytes.NewReader(j))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// aliases in a different order are equivalent
+	reordered := CloneSchema(s).(*FixedObjectSchema)
+	reordered.Fields[1].Aliases = []string{"FullName", "Name"}
+
+	fp, err := Fingerprint64(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := FingerprintSHA256(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, other := range []Schema{s, fromBinary, fromJSON, reordered} {
+		otherFP, err := Fingerprint64(other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		otherSum, err := FingerprintSHA256(other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if otherFP != fp || otherSum != sum {
+			t.Errorf("%d: fingerprints do not match", i)
+		}
+	}
+
+	// the canonical form must not modify the schema
+	if s.(*FixedObjectSchema).Fields[1].Aliases[0] != "Name" {
+		t.Error("unexpected change to schema aliases")
+	}
+
+	// different schemas have different fingerprints
+	changed := CloneSchema(s).(*FixedObjectSchema)
+	changed.Fields[0].Schema.(*VarIntSchema).SetNullable(true)
+	changedFP, err := Fingerprint64(changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changedFP == fp {
+		t.Error("expected fingerprint to change")
+	}
+}
+
+func TestFingerprintBuiltins(t *testing.T) {
+
+	schemas := []Schema{
+		&BoolSchema{},
+		&VarIntSchema{},
+		&VarIntSchema{Signed: true},
+		&FixedIntSchema{Bits: 16},
+		&FloatSchema{Bits: 32},
+		&ComplexSchema{Bits: 128},
+		&EnumSchema{Values: map[int]string{1: "one"}},
+		&VarStringSchema{},
+		&FixedStringSchema{Length: 3},
+		&VarArraySchema{Element: &BoolSchema{}},
+		&FixedArraySchema{Length: 2, Element: &BoolSchema{}},
+		&VarObjectSchema{Key: &VarStringSchema{}, Value: &BoolSchema{}},
+		&FixedObjectSchema{},
+		&VariantSchema{},
+		&SchemaSchema{},
+		&DateSchema{},
+	}
+
+	seen := make(map[uint64]int)
+	for i, s := range schemas {
+		fp, err := Fingerprint64(s)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if j, ok := seen[fp]; ok {
+			t.Errorf("%d: fingerprint matches schema %d", i, j)
+		}
+		seen[fp] = i
+	}
+
+}
@@ -0,0 +1,176 @@
+package schemer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"reflect"
+)
+
+// ErrChecksumMismatch is returned (wrapped) when the checksum of a value does
+// not match the checksum that was written with it
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// newCRC32C returns a hash.Hash computing the CRC-32C (Castagnoli) checksum
+func newCRC32C() hash.Hash {
+	return crc32.New(crc32c)
+}
+
+// appendSum appends the checksum computed by h to dst. The values of 32-bit
+// and 64-bit hashes (see hash.Hash32 and hash.Hash64) are appended in
+// little-endian byte order; other checksums are appended as returned by
+// h.Sum.
+func appendSum(dst []byte, h hash.Hash) []byte {
+	switch h := h.(type) {
+	case hash.Hash32:
+		var sum [4]byte
+		binary.LittleEndian.PutUint32(sum[:], h.Sum32())
+		return append(dst, sum[:]...)
+	case hash.Hash64:
+		var sum [8]byte
+		binary.LittleEndian.PutUint64(sum[:], h.Sum64())
+		return append(dst, sum[:]...)
+	}
+	return h.Sum(dst)
+}
+
+// ChecksumWriter writes values encoded with a Schema, each followed by a
+// checksum so that damaged values are detected by a ChecksumReader.
+// Each value is written as an unsigned variable-size integer length, the
+// encoded value, and the checksum of the length and encoded value. By
+// default, the checksum is the CRC-32C (Castagnoli) checksum of the data.
+// Like the checksums of record logs, 32-bit and 64-bit checksums are written
+// in little-endian byte order (see appendSum).
+type ChecksumWriter struct {
+	w       io.Writer
+	plans   planCache
+	newHash func() hash.Hash
+
+	payload []byte
+	out     []byte
+}
+
+// NewChecksumWriter returns a ChecksumWriter that writes values encoded with
+// schema s to w
+func NewChecksumWriter(w io.Writer, s Schema) *ChecksumWriter {
+	return &ChecksumWriter{
+		w:       w,
+		plans:   planCache{schema: s},
+		newHash: newCRC32C,
+	}
+}
+
+// SetHash sets the hash function used to compute checksums (i.e. a 64-bit
+// xxHash). The reader must use the same hash function.
+func (cw *ChecksumWriter) SetHash(newHash func() hash.Hash) {
+	cw.newHash = newHash
+}
+
+// Encode writes the encoded value of i and its checksum to the output stream
+// using a single call to Write
+func (cw *ChecksumWriter) Encode(i interface{}) error {
+	return cw.EncodeValue(reflect.ValueOf(i))
+}
+
+// EncodeValue writes the encoded value of v and its checksum to the output
+// stream using a single call to Write
+func (cw *ChecksumWriter) EncodeValue(v reflect.Value) error {
+	payload, err := cw.plans.appendValue(cw.payload[:0], v)
+	cw.payload = payload
+	if err != nil {
+		return err
+	}
+	if len(payload) > MaxRecordSize {
+		return fmt.Errorf("value size %d exceeds maximum of %d", len(payload), MaxRecordSize)
+	}
+
+	out := appendUvarint(cw.out[:0], uint64(len(payload)))
+	out = append(out, payload...)
+	h := cw.newHash()
+	h.Write(out)
+	out = appendSum(out, h)
+	cw.out = out
+
+	_, err = cw.w.Write(out)
+	return err
+}
+
+// ChecksumReader reads values written by a ChecksumWriter. The checksum of
+// each value is verified before the value is decoded; if it does not match,
+// an error wrapping ErrChecksumMismatch is returned.
+// Values larger than MaxRecordSize are considered to be damaged.
+type ChecksumReader struct {
+	r       io.Reader
+	plans   planCache
+	newHash func() hash.Hash
+
+	buf     []byte
+	payload bytes.Reader
+}
+
+// NewChecksumReader returns a ChecksumReader that reads values encoded with
+// schema s from r. The ChecksumReader does not read beyond the end of each
+// value.
+func NewChecksumReader(r io.Reader, s Schema) *ChecksumReader {
+	return &ChecksumReader{
+		r:       r,
+		plans:   planCache{schema: s},
+		newHash: newCRC32C,
+	}
+}
+
+// SetHash sets the hash function used to compute checksums. It must match the
+// hash function of the writer.
+func (cr *ChecksumReader) SetHash(newHash func() hash.Hash) {
+	cr.newHash = newHash
+}
+
+// Decode reads the next value from the input stream, verifies its checksum,
+// and stores it in i, which must be a non-nil pointer
+func (cr *ChecksumReader) Decode(i interface{}) error {
+	return cr.DecodeValue(reflect.ValueOf(i))
+}
+
+// DecodeValue reads the next value from the input stream, verifies its
+// checksum, and stores it in v, which must be a non-nil pointer
+func (cr *ChecksumReader) DecodeValue(v reflect.Value) error {
+	length, err := ReadUvarint(cr.r)
+	if err != nil {
+		return err
+	}
+	if length > MaxRecordSize {
+		return fmt.Errorf("%w: invalid value length %d", ErrChecksumMismatch, length)
+	}
+
+	h := cr.newHash()
+	header := appendUvarint(cr.buf[:0], length)
+	n := len(header)
+	size := n + int(length) + h.Size()
+	if cap(header) < size {
+		header = make([]byte, n, size)
+		appendUvarint(header[:0], length)
+	}
+	buf := header[:size]
+	cr.buf = buf
+
+	_, err = io.ReadFull(cr.r, buf[n:])
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+
+	data, sum := buf[:size-h.Size()], buf[size-h.Size():]
+	h.Write(data)
+	if !bytes.Equal(appendSum(nil, h), sum) {
+		return ErrChecksumMismatch
+	}
+
+	cr.payload.Reset(data[n:])
+	return cr.plans.decodeAll(&cr.payload, v, "value")
+}
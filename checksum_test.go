@@ -0,0 +1,100 @@
+package schemer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"reflect"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+
+	s, err := SchemaOf(testRecord{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := []testRecord{
+		{ID: 1, Name: "one", Tags: []string{"a"}},
+		{ID: 2, Name: "two", Tags: []string{"b"}, Inner: &embeddedStruct{Int1: 2}},
+	}
+
+	for _, newHash := range []func() hash.Hash{nil, sha256.New} {
+		var buf bytes.Buffer
+		cw := NewChecksumWriter(&buf, s)
+		if newHash != nil {
+			cw.SetHash(newHash)
+		}
+		for _, v := range values {
+			if err = cw.Encode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		cr := NewChecksumReader(bytes.NewReader(buf.Bytes()), s)
+		if newHash != nil {
+			cr.SetHash(newHash)
+		}
+		for _, expected := range values {
+			var v testRecord
+			if err = cr.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(v, expected) {
+				t.Errorf("expected %v; got %v", expected, v)
+			}
+		}
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+
+	s := &VarArraySchema{Element: &VarStringSchema{}}
+	var buf bytes.Buffer
+	cw := NewChecksumWriter(&buf, s)
+	if err := cw.Encode([]string{"hello", "world"}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	for i := range data {
+		damaged := append([]byte(nil), data...)
+		damaged[i] ^= 0x40
+
+		var v []string
+		err := NewChecksumReader(bytes.NewReader(damaged), s).Decode(&v)
+		if err == nil {
+			t.Errorf("%d: expected error decoding damaged value", i)
+		}
+		if i > 0 && !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("%d: expected ErrChecksumMismatch; got %v", i, err)
+		}
+	}
+
+	// a damaged length must not cause a huge allocation
+	damaged := append([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0x7F}, data[1:]...)
+	var v []string
+	err := NewChecksumReader(bytes.NewReader(damaged), s).Decode(&v)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch; got %v", err)
+	}
+}
+
+// checksums are written in the same byte order as the checksums of record logs
+func TestChecksumByteOrder(t *testing.T) {
+
+	var buf bytes.Buffer
+	cw := NewChecksumWriter(&buf, &VarStringSchema{})
+	if err := cw.Encode("hello"); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	n := len(data) - 4
+	sum := crc32.Checksum(data[:n], crc32c)
+	if binary.LittleEndian.Uint32(data[n:]) != sum {
+		t.Errorf("expected little-endian CRC-32C %08x; got % x", sum, data[n:])
+	}
+}
@@ -0,0 +1,382 @@
+package schemer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Incompatibility describes a value that was encoded with a writer schema and
+// cannot be decoded into a value described by a reader schema
+type Incompatibility struct {
+	// Path is the location of the value within the writer schema, using struct
+	// field names (i.e. "Address.Street"), "[]" for array elements, and
+	// "{key}" and "{value}" for the keys and values of maps. Path is empty if
+	// the root value is incompatible.
+	Path string
+
+	Writer Schema
+	Reader Schema
+	Reason string
+}
+
+func (inc Incompatibility) String() string {
+	if inc.Path == "" {
+		return inc.Reason
+	}
+	return inc.Path + ": " + inc.Reason
+}
+
+// Error implements the error interface
+func (inc Incompatibility) Error() string {
+	return inc.String()
+}
+
+// CheckCompatibility returns a list of incompatibilities that would prevent
+// values encoded with the writer schema from being decoded into Go values of
+// the types described by the reader schema (i.e. reader.GoType()). If the
+// returned list is empty, the schemas are compatible.
+// The rules followed are the same as those of the DecodeValue implementations.
+// Rules that depend on weak decoding use the WeakDecoding flag of the writer
+// schema, since that is the schema used for decoding.
+// Incompatibilities that depend on the encoded values themselves (i.e. integer
+// overflow or loss of floating-point precision) are not reported, and values
+// of variant schemas are assumed to be compatible.
+func CheckCompatibility(writer, reader Schema) []Incompatibility {
+	c := compatibilityChecker{}
+	c.check("", writer, reader)
+	return c.list
+}
+
+// compatibilityChecker accumulates incompatibilities while walking a pair of
+// schemas
+type compatibilityChecker struct {
+	list []Incompatibility
+}
+
+func (c *compatibilityChecker) report(path string, w, r Schema, format string, args ...interface{}) {
+	c.list = append(c.list, Incompatibility{
+		Path:   path,
+		Writer: w,
+		Reader: r,
+		Reason: fmt.Sprintf(format, args...),
+	})
+}
+
+// destination describes the Go type that values are decoded into
+type destination struct {
+	kind reflect.Kind
+	t    reflect.Type // nil for built-in schemas
+}
+
+// destinationOf returns the kind of Go value described by the reader schema r
+func destinationOf(r Schema) destination {
+	switch r := r.(type) {
+	case *VarIntSchema:
+		if r.Signed {
+			return destination{kind: reflect.Int}
+		}
+		return destination{kind: reflect.Uint}
+	case *FixedIntSchema:
+		if r.Signed {
+			return destination{kind: reflect.Int}
+		}
+		return destination{kind: reflect.Uint}
+	case *EnumSchema:
+		return destination{kind: reflect.Int}
+	case *FloatSchema:
+		return destination{kind: reflect.Float64}
+	case *ComplexSchema:
+		return destination{kind: reflect.Complex128}
+	case *BoolSchema:
+		return destination{kind: reflect.Bool}
+	case *VarStringSchema, *FixedStringSchema:
+		return destination{kind: reflect.String}
+	case *VarArraySchema, *VarBytesSchema:
+		return destination{kind: reflect.Slice}
+	case *FixedArraySchema, *FixedBytesSchema:
+		return destination{kind: reflect.Array}
+	case *VarObjectSchema:
+		return destination{kind: reflect.Map}
+	case *FixedObjectSchema:
+		return destination{kind: reflect.Struct}
+	case *VariantSchema, *SchemaSchema:
+		return destination{kind: reflect.Interface}
+	}
+
+	// Schemas of custom types
+	t, _ := derefType(r.GoType())
+	return destination{kind: t.Kind(), t: t}
+}
+
+func isNumericKind(k reflect.Kind) bool {
+	return isIntKind(k) || isUintKind(k) ||
+		k == reflect.Float32 || k == reflect.Float64 ||
+		k == reflect.Complex64 || k == reflect.Complex128
+}
+
+// isIntSchema returns true if s is a VarIntSchema or FixedIntSchema
+func isIntSchema(s Schema) bool {
+	switch s.(type) {
+	case *VarIntSchema, *FixedIntSchema:
+		return true
+	}
+	return false
+}
+
+func (c *compatibilityChecker) check(path string, w, r Schema) {
+	if w == nil || r == nil {
+		c.report(path, w, r, "missing schema")
+		return
+	}
+
+	// Anything can be decoded to an empty interface, and the values of
+	// variants are only known when they are decoded
+	if _, ok := r.(*VariantSchema); ok {
+		return
+	}
+	if _, ok := w.(*VariantSchema); ok {
+		return
+	}
+
+	if nullable(w) && !nullable(r) {
+		c.report(path, w, r, "writer is nullable, but reader is not")
+	}
+
+	weak := false
+	if opt, ok := w.(interface{ WeakDecoding() bool }); ok {
+		weak = opt.WeakDecoding()
+	}
+
+	dest := destinationOf(r)
+	k := dest.kind
+	incompatible := func() {
+		c.report(path, w, r, "cannot decode %s to %s", schemaName(w), schemaName(r))
+	}
+	needsWeak := func() {
+		c.report(path, w, r, "cannot decode %s to %s without weak decoding",
+			schemaName(w), schemaName(r))
+	}
+
+	switch w := w.(type) {
+	case *VarIntSchema, *FixedIntSchema:
+		switch {
+		case isNumericKind(k):
+		case k == reflect.Bool || k == reflect.String:
+			if !weak {
+				needsWeak()
+			}
+		default:
+			incompatible()
+		}
+
+	case *FloatSchema:
+		switch {
+		case isIntKind(k) || isUintKind(k) || k == reflect.Float32 || k == reflect.Float64:
+		case k == reflect.Complex64 || k == reflect.Complex128 || k == reflect.String:
+			if !weak {
+				needsWeak()
+			}
+		default:
+			incompatible()
+		}
+
+	case *ComplexSchema:
+		switch {
+		case isNumericKind(k):
+		case k == reflect.String:
+			if !weak {
+				needsWeak()
+			}
+		case k == reflect.Array || k == reflect.Slice:
+			if !weak {
+				needsWeak()
+				break
+			}
+			var el Schema
+			switch r := r.(type) {
+			case *FixedArraySchema:
+				if r.Length != 2 {
+					c.report(path, w, r, "complex numbers can only be decoded to arrays of length 2")
+				}
+				el = r.Element
+			case *VarArraySchema:
+				el = r.Element
+			}
+			if _, ok := el.(*FloatSchema); !ok {
+				c.report(path, w, r, "complex numbers can only be decoded to arrays of floats")
+			}
+		default:
+			incompatible()
+		}
+
+	case *BoolSchema:
+		switch {
+		case k == reflect.Bool:
+		case isIntKind(k) || isUintKind(k) || k == reflect.String:
+			if !weak {
+				needsWeak()
+			}
+		default:
+			incompatible()
+		}
+
+	case *EnumSchema:
+		switch {
+		case isIntKind(k) || isUintKind(k):
+		case k == reflect.String:
+			if !weak {
+				needsWeak()
+			}
+		default:
+			incompatible()
+		}
+
+	case *VarStringSchema, *FixedStringSchema:
+		switch {
+		case k == reflect.String:
+		case isIntKind(k) || isUintKind(k) || k == reflect.Float32 || k == reflect.Float64:
+		case k == reflect.Bool:
+			if !weak {
+				needsWeak()
+			}
+		default:
+			incompatible()
+		}
+
+	case *VarBytesSchema:
+		switch r.(type) {
+		case *VarBytesSchema:
+		case *VarStringSchema, *FixedStringSchema:
+			if !weak {
+				needsWeak()
+			}
+		default:
+			incompatible()
+		}
+
+	case *FixedBytesSchema:
+		switch r := r.(type) {
+		case *VarBytesSchema:
+		case *FixedBytesSchema:
+			if w.Length != r.Length {
+				c.report(path, w, r, "binary string length %d does not match %d", w.Length, r.Length)
+			}
+		case *VarStringSchema, *FixedStringSchema:
+			if !weak {
+				needsWeak()
+			}
+		default:
+			incompatible()
+		}
+
+	case *VarArraySchema:
+		// Byte slices that were encoded as arrays of integers can be read as
+		// binary strings
+		if _, ok := r.(*VarBytesSchema); ok && isIntSchema(w.Element) {
+			break
+		}
+		r, ok := r.(*VarArraySchema)
+		if !ok {
+			incompatible()
+			break
+		}
+		c.check(path+"[]", w.Element, r.Element)
+
+	case *FixedArraySchema:
+		if rb, ok := r.(*FixedBytesSchema); ok && isIntSchema(w.Element) {
+			if w.Length != rb.Length {
+				c.report(path, w, r, "array length %d does not match %d", w.Length, rb.Length)
+			}
+			break
+		}
+		r, ok := r.(*FixedArraySchema)
+		if !ok {
+			incompatible()
+			break
+		}
+		if w.Length != r.Length {
+			c.report(path, w, r, "array length %d does not match %d", w.Length, r.Length)
+			break
+		}
+		c.check(path+"[]", w.Element, r.Element)
+
+	case *VarObjectSchema:
+		r, ok := r.(*VarObjectSchema)
+		if !ok {
+			incompatible()
+			break
+		}
+		c.check(path+"{key}", w.Key, r.Key)
+		c.check(path+"{value}", w.Value, r.Value)
+
+	case *FixedObjectSchema:
+		r, ok := r.(*FixedObjectSchema)
+		if !ok {
+			incompatible()
+			break
+		}
+		for _, wf := range w.Fields {
+			// writer fields without a matching reader field are skipped
+			rf := findObjectField(wf.Aliases, r)
+			if rf == nil {
+				continue
+			}
+			name := ""
+			if len(wf.Aliases) > 0 {
+				name = wf.Aliases[0]
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			c.check(name, wf.Schema, rf.Schema)
+		}
+
+	case *SchemaSchema:
+		if _, ok := r.(*SchemaSchema); !ok {
+			incompatible()
+		}
+
+	case *DateSchema:
+		switch {
+		// Note: dates can be decoded to signed integers (as nanoseconds), which
+		// are described by signed integer schemas
+		case dest.t == timeType || isIntKind(k):
+		case k == reflect.String:
+			if !weak {
+				needsWeak()
+			}
+		default:
+			incompatible()
+		}
+
+	default:
+		// Custom types can only be decoded by the same type of schema
+		if reflect.TypeOf(w) != reflect.TypeOf(r) {
+			incompatible()
+		}
+	}
+}
+
+// findObjectField returns the field of r whose aliases include one of the
+// specified aliases, or nil if there is no such field
+func findObjectField(aliases []string, r *FixedObjectSchema) *ObjectField {
+	for _, alias := range aliases {
+		for i := range r.Fields {
+			for _, fieldAlias := range r.Fields[i].Aliases {
+				if alias == fieldAlias {
+					return &r.Fields[i]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// schemaName returns a short, human-readable name for the type of s
+func schemaName(s Schema) string {
+	name := reflect.TypeOf(s).String()
+	name = strings.TrimPrefix(name, "*")
+	name = strings.TrimPrefix(name, "schemer.")
+	return name
+}
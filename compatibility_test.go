@@ -0,0 +1,113 @@
+package schemer
+
+import (
+	"testing"
+	"time"
+)
+
+type compatWriter struct {
+	ID      int
+	Name    string
+	Score   float64
+	Tags    []string
+	Created time.Time
+	Dropped bool
+	Counts  map[string]uint
+	Nested  struct {
+		Flag  *bool
+		Label string
+	}
+}
+
+type compatReader struct {
+	ID      float32
+	Name    string
+	Score   int
+	Tags    []bool
+	Created int64
+	Counts  map[string]string
+	Nested  struct {
+		Flag  bool
+		Label string
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+
+	writer, err := SchemaOf(compatWriter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := SchemaOf(compatReader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if list := CheckCompatibility(writer, writer); len(list) != 0 {
+		t.Errorf("expected schema to be compatible with itself: %v", list)
+	}
+
+	list := CheckCompatibility(writer, reader)
+	expected := map[string]bool{
+		"Tags[]":        true, // string to bool requires weak decoding
+		"Counts{value}": true, // uint to string requires weak decoding
+		"Nested.Flag":   true, // nullable to non-nullable
+	}
+	for _, inc := range list {
+		if !expected[inc.Path] {
+			t.Errorf("unexpected incompatibility %v", inc)
+		}
+		delete(expected, inc.Path)
+	}
+	for path := range expected {
+		t.Errorf("expected incompatibility at %s", path)
+	}
+
+	// weak decoding
+	writer = CloneSchema(writer)
+	tags := writer.(*FixedObjectSchema).Fields[3].Schema.(*VarArraySchema)
+	tags.Element.(*VarStringSchema).SetWeakDecoding(true)
+	for _, inc := range CheckCompatibility(writer, reader) {
+		if inc.Path == "Tags[]" {
+			t.Errorf("unexpected incompatibility %v", inc)
+		}
+	}
+}
+
+func TestCheckCompatibilityRoot(t *testing.T) {
+
+	tests := []struct {
+		writer, reader Schema
+		compatible     bool
+	}{
+		{&VarIntSchema{Signed: true}, &FloatSchema{Bits: 32}, true},
+		{&FloatSchema{Bits: 64}, &ComplexSchema{Bits: 128}, false},
+		{&BoolSchema{}, &VarStringSchema{}, false},
+		{&VarStringSchema{}, &VarIntSchema{}, true},
+		{&VarStringSchema{}, &FixedObjectSchema{}, false},
+		{&FixedArraySchema{Length: 3, Element: &BoolSchema{}},
+			&FixedArraySchema{Length: 4, Element: &BoolSchema{}}, false},
+		{&VarArraySchema{Element: &BoolSchema{}},
+			&FixedArraySchema{Length: 1, Element: &BoolSchema{}}, false},
+		{&VarObjectSchema{Key: &VarStringSchema{}, Value: &BoolSchema{}},
+			&VariantSchema{}, true},
+		{&SchemaSchema{}, &SchemaSchema{}, true},
+		{&DateSchema{}, &ipv4Schema{}, false},
+		{&ipv4Schema{}, &ipv4Schema{}, true},
+	}
+
+	for i, test := range tests {
+		list := CheckCompatibility(test.writer, test.reader)
+		if test.compatible && len(list) > 0 {
+			t.Errorf("%d: unexpected incompatibilities %v", i, list)
+		}
+		if !test.compatible && len(list) == 0 {
+			t.Errorf("%d: expected incompatibility", i)
+		}
+		for _, inc := range list {
+			if inc.Path != "" {
+				t.Errorf("%d: unexpected path %q", i, inc.Path)
+			}
+		}
+	}
+}
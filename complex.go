@@ -2,7 +2,6 @@ package schemer
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -78,78 +77,73 @@ func (s *ComplexSchema) Encode(w io.Writer, i interface{}) error {
 
 // EncodeValue uses the schema to write the encoded value of v to the output stream
 func (s *ComplexSchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
+
+// AppendEncode appends the encoded value of i to dst and returns the extended slice
+func (s *ComplexSchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
+
+// AppendEncodeValue appends the encoded value of v to dst and returns the extended slice
+func (s *ComplexSchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
 
 	// just double check the schema they are using
 	if !s.Valid() {
-		return fmt.Errorf("cannot encode using invalid ComplexNumber schema")
+		return dst, fmt.Errorf("cannot encode using invalid ComplexNumber schema")
 	}
 
-	done, err := PreEncode(w, &v, s.Nullable())
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
 	if err != nil || done {
-		return err
+		return dst, err
 	}
 
-	t := v.Type()
-	k := t.Kind()
-
+	k := v.Kind()
 	if k != reflect.Complex64 && k != reflect.Complex128 {
-		return fmt.Errorf("ComplexSchema only supports encoding Complex64 and Complex128 values")
+		return dst, fmt.Errorf("ComplexSchema only supports encoding Complex64 and Complex128 values")
 	}
 	complex := v.Complex()
 
 	switch s.Bits {
 	case 64:
-		if k == reflect.Float64 {
-			return fmt.Errorf("32bit FloatSchema schema cannot encode 64 bit values")
-		}
 		r := math.Float32bits(float32(real(complex)))
 		imaginary := math.Float32bits(float32(imag(complex)))
 
-		n, err := w.Write([]byte{
+		return append(dst,
 			byte(r),
-			byte(r >> 8),
-			byte(r >> 16),
-			byte(r >> 24),
+			byte(r>>8),
+			byte(r>>16),
+			byte(r>>24),
 			byte(imaginary),
-			byte(imaginary >> 8),
-			byte(imaginary >> 16),
-			byte(imaginary >> 24),
-		})
-		if err == nil && n != 8 {
-			err = errors.New("unexpected number of bytes written")
-		}
-		return err
+			byte(imaginary>>8),
+			byte(imaginary>>16),
+			byte(imaginary>>24),
+		), nil
 
 	case 128:
 		r := math.Float64bits(real(complex))
 		imaginary := math.Float64bits(imag(complex))
 
-		n, err := w.Write([]byte{
+		return append(dst,
 			byte(r),
-			byte(r >> 8),
-			byte(r >> 16),
-			byte(r >> 24),
-			byte(r >> 32),
-			byte(r >> 40),
-			byte(r >> 48),
-			byte(r >> 56),
+			byte(r>>8),
+			byte(r>>16),
+			byte(r>>24),
+			byte(r>>32),
+			byte(r>>40),
+			byte(r>>48),
+			byte(r>>56),
 			byte(imaginary),
-			byte(imaginary >> 8),
-			byte(imaginary >> 16),
-			byte(imaginary >> 24),
-			byte(imaginary >> 32),
-			byte(imaginary >> 40),
-			byte(imaginary >> 48),
-			byte(imaginary >> 56),
-		})
-		if err == nil && n != 16 {
-			err = errors.New("unexpected number of bytes written")
-		}
-		return err
-	default:
-		// error
+			byte(imaginary>>8),
+			byte(imaginary>>16),
+			byte(imaginary>>24),
+			byte(imaginary>>32),
+			byte(imaginary>>40),
+			byte(imaginary>>48),
+			byte(imaginary>>56),
+		), nil
 	}
-	return nil
+	return dst, nil
 }
 
 // Decode uses the schema to read the next encoded value from the input stream and store it in i
@@ -160,6 +154,11 @@ func (s *ComplexSchema) Decode(r io.Reader, i interface{}) error {
 	return s.DecodeValue(r, reflect.ValueOf(i))
 }
 
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *ComplexSchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
 // DecodeValue uses the schema to read the next encoded value from the input stream and store it in v
 func (s *ComplexSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 
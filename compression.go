@@ -0,0 +1,127 @@
+package schemer
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/lzw"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// CompressionCodec compresses and decompresses blocks of encoded values (see
+// BatchWriter). Codecs are identified by name and must be registered using
+// RegisterCodec before they can be used.
+type CompressionCodec struct {
+	// NewWriter returns a WriteCloser that compresses data written to it and
+	// writes the compressed data to w. Close is called at the end of each
+	// block.
+	NewWriter func(w io.Writer) (io.WriteCloser, error)
+
+	// NewReader returns a ReadCloser that decompresses data read from r
+	NewReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+// codecs holds the registered codecs, including the built-in ones
+var codecs = struct {
+	sync.RWMutex
+	m map[string]CompressionCodec
+}{m: map[string]CompressionCodec{
+	"none": {
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return nopWriteCloser{w}, nil
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(r), nil
+		},
+	},
+	"flate": {
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(w, flate.DefaultCompression)
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return flate.NewReader(r), nil
+		},
+	},
+	"gzip": {
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	"zlib": {
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return zlib.NewWriter(w), nil
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return zlib.NewReader(r)
+		},
+	},
+	"lzw": {
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return lzw.NewWriter(w, lzw.LSB, 8), nil
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return lzw.NewReader(r, lzw.LSB, 8), nil
+		},
+	},
+}}
+
+// RegisterCodec registers a CompressionCodec with the specified name. Codecs for
+// "none", "flate", "gzip", "zlib", and "lzw" are registered by default.
+// An error is returned if a codec with the same name is already registered.
+func RegisterCodec(name string, c CompressionCodec) error {
+	if name == "" {
+		return fmt.Errorf("codec name is required")
+	}
+	if c.NewWriter == nil || c.NewReader == nil {
+		return fmt.Errorf("codec %q must provide NewWriter and NewReader", name)
+	}
+
+	codecs.Lock()
+	defer codecs.Unlock()
+
+	if _, ok := codecs.m[name]; ok {
+		return fmt.Errorf("codec %q is already registered", name)
+	}
+	codecs.m[name] = c
+	return nil
+}
+
+// lookupCodec returns the CompressionCodec with the specified name
+func lookupCodec(name string) (CompressionCodec, error) {
+	codecs.RLock()
+	c, ok := codecs.m[name]
+	codecs.RUnlock()
+
+	if !ok {
+		return CompressionCodec{}, fmt.Errorf("unknown codec %q", name)
+	}
+	return c, nil
+}
+
+// Codecs returns the names of the registered codecs in sorted order
+func Codecs() []string {
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	names := make([]string, 0, len(codecs.m))
+	for name := range codecs.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nopWriteCloser adds a no-op Close method to an io.Writer
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
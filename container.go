@@ -0,0 +1,451 @@
+package schemer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Object container files store a large number of values encoded with a single
+// schema in blocks, each followed by a sync marker. See encoding-format.md for
+// details.
+
+// FileMagic is the first 4 bytes of an object container file
+var FileMagic = []byte("Smr\x01")
+
+// DefaultBlockSize is the default size of blocks written by a FileWriter
+const DefaultBlockSize = 64 * 1024
+
+// syncSize is the size of the sync marker written after each block
+const syncSize = 16
+
+// planCache caches the Plans used to encode or decode values of each Go type
+// using a single schema. A planCache is not safe for concurrent use.
+type planCache struct {
+	schema Schema
+	plans  map[reflect.Type]*Plan
+}
+
+// plan returns the Plan for Go type t
+func (c *planCache) plan(t reflect.Type) (*Plan, error) {
+	if p, ok := c.plans[t]; ok {
+		return p, nil
+	}
+	p, err := Compile(c.schema, t)
+	if err != nil {
+		return nil, err
+	}
+	if c.plans == nil {
+		c.plans = make(map[reflect.Type]*Plan)
+	}
+	c.plans[t] = p
+	return p, nil
+}
+
+// appendValue appends the encoded value of v to b
+func (c *planCache) appendValue(b []byte, v reflect.Value) ([]byte, error) {
+	if !v.IsValid() {
+		w := appendWriter{b}
+		err := c.schema.EncodeValue(&w, v)
+		return w.b, err
+	}
+	p, err := c.plan(v.Type())
+	if err != nil {
+		return b, err
+	}
+	return p.appendValue(b, v)
+}
+
+// decodeValue reads the next encoded value from r and stores it in v, which
+// must be a non-nil pointer
+func (c *planCache) decodeValue(r io.Reader, v reflect.Value) error {
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("decode destination must be a non-nil pointer")
+	}
+	p, err := c.plan(v.Type().Elem())
+	if err != nil {
+		return err
+	}
+	return p.DecodeValue(r, v)
+}
+
+// decodeNext reads the next value from r, which holds the encoded values of a
+// block, and stores it in v. io.EOF is reported as io.ErrUnexpectedEOF since
+// the block ended in the middle of a value.
+func (c *planCache) decodeNext(r *bytes.Reader, v reflect.Value) error {
+	err := c.decodeValue(r, v)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// decodeAll reads the value from r, which holds a single encoded value (i.e.
+// the payload of a frame or record), and stores it in v. what describes the
+// payload in the error returned if the value does not use all of r.
+func (c *planCache) decodeAll(r *bytes.Reader, v reflect.Value, what string) error {
+	err := c.decodeNext(r, v)
+	if err == nil && r.Len() > 0 {
+		err = fmt.Errorf("%s has %d bytes of trailing data", what, r.Len())
+	}
+	return err
+}
+
+// blockWriter implements the Append, Flush, and Close methods shared by
+// FileWriter, BatchWriter, and RecordWriter. Values are encoded into a block,
+// which is passed to writeBlock once it holds maxBytes bytes or maxValues
+// values. A record of a record log is a block holding one value.
+type blockWriter struct {
+	plans      planCache
+	maxBytes   int
+	maxValues  int
+	writeBlock func(count int, block []byte) error
+
+	block  []byte // encoded values of the current block
+	count  int    // number of values in the current block
+	closed bool
+}
+
+// append encodes v and adds it to the current block. The block is written
+// once it is full. what names the writer in the error returned once it is
+// closed.
+func (bw *blockWriter) append(v reflect.Value, what string) error {
+	if bw.closed {
+		return fmt.Errorf("cannot append to closed %s", what)
+	}
+
+	n := len(bw.block)
+	b, err := bw.plans.appendValue(bw.block, v)
+	if err != nil {
+		// Discard the partially encoded value
+		bw.block = b[:n]
+		return err
+	}
+	bw.block = b
+	bw.count++
+
+	if len(bw.block) >= bw.maxBytes || bw.count >= bw.maxValues {
+		return bw.flush()
+	}
+	return nil
+}
+
+// flush writes the current block, if it is not empty. The block is cleared
+// even if it cannot be written.
+func (bw *blockWriter) flush() error {
+	if bw.count == 0 {
+		return nil
+	}
+	err := bw.writeBlock(bw.count, bw.block)
+	bw.block = bw.block[:0]
+	bw.count = 0
+	return err
+}
+
+// close flushes the current block; values cannot be appended afterwards
+func (bw *blockWriter) close() error {
+	if bw.closed {
+		return nil
+	}
+	bw.closed = true
+	return bw.flush()
+}
+
+// blockReader implements the Next, DecodeValue, and Err methods shared by
+// FileReader, BatchReader, and RecordReader, which read a sequence of blocks
+// of encoded values. A record of a record log is a block holding one value.
+type blockReader struct {
+	plans planCache
+
+	// readBlock reads the next block and returns the number of values in it
+	// and their encoding. io.EOF indicates the end of the input.
+	readBlock func() (uint64, []byte, error)
+
+	// single is true if each block holds one value, which must use all of
+	// the block
+	single bool
+
+	block     bytes.Reader // encoded values of the current block
+	remaining uint64       // number of values left in the current block
+	err       error
+}
+
+// next prepares the next value for reading. It returns false when there are
+// no more values or an error occurs. Calling next several times without
+// calling decodeValue does not skip values.
+func (br *blockReader) next() bool {
+	for br.remaining == 0 {
+		if br.err != nil {
+			return false
+		}
+		var count uint64
+		var data []byte
+		count, data, br.err = br.readBlock()
+		br.block.Reset(data)
+		br.remaining = count
+	}
+	return true
+}
+
+// decodeValue reads the next value and stores it in v, which must be a
+// non-nil pointer. io.EOF is returned if there are no more values.
+func (br *blockReader) decodeValue(v reflect.Value) error {
+	if !br.next() {
+		return br.err
+	}
+	br.remaining--
+	if br.single {
+		return br.plans.decodeAll(&br.block, v, "record")
+	}
+	return br.plans.decodeNext(&br.block, v)
+}
+
+// errValue returns the first error encountered by next, other than io.EOF
+func (br *blockReader) errValue() error {
+	if br.err == io.EOF {
+		return nil
+	}
+	return br.err
+}
+
+// FileWriter writes values to an object container file
+type FileWriter struct {
+	w      io.Writer
+	sync   [syncSize]byte
+	blocks blockWriter
+	out    []byte
+}
+
+// NewFileWriter writes the header of an object container file to w and
+// returns a FileWriter that writes values encoded with schema s. metadata may
+// be nil.
+func NewFileWriter(w io.Writer, s Schema, metadata map[string][]byte) (*FileWriter, error) {
+	m, ok := s.(Marshaler)
+	if !ok {
+		return nil, fmt.Errorf("schema does not implement MarshalSchemer")
+	}
+	schemaBytes, err := m.MarshalSchemer()
+	if err != nil {
+		return nil, err
+	}
+
+	fw := &FileWriter{w: w}
+	fw.blocks = blockWriter{
+		plans:      planCache{schema: s},
+		maxBytes:   DefaultBlockSize,
+		maxValues:  math.MaxInt,
+		writeBlock: fw.writeBlock,
+	}
+	_, err = io.ReadFull(rand.Reader, fw.sync[:])
+	if err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(nil), FileMagic...)
+	header = append(header, schemaBytes...)
+
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	header = appendUvarint(header, uint64(len(keys)))
+	for _, k := range keys {
+		header = appendUvarint(header, uint64(len(k)))
+		header = append(header, k...)
+		header = appendUvarint(header, uint64(len(metadata[k])))
+		header = append(header, metadata[k]...)
+	}
+	header = append(header, fw.sync[:]...)
+
+	_, err = w.Write(header)
+	if err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
+
+// SetBlockSize sets the approximate size of the blocks written to the file.
+// A block is written once the encoded values appended to it reach n bytes.
+func (fw *FileWriter) SetBlockSize(n int) {
+	fw.blocks.maxBytes = n
+}
+
+// Schema returns the schema used to encode values
+func (fw *FileWriter) Schema() Schema {
+	return fw.blocks.plans.schema
+}
+
+// Append encodes v and adds it to the current block. The block is written
+// once it is full.
+func (fw *FileWriter) Append(v interface{}) error {
+	return fw.blocks.append(reflect.ValueOf(v), "FileWriter")
+}
+
+// Flush writes the current block to the file, if it is not empty
+func (fw *FileWriter) Flush() error {
+	return fw.blocks.flush()
+}
+
+// writeBlock writes a block holding count encoded values to the file
+func (fw *FileWriter) writeBlock(count int, block []byte) error {
+	out := appendUvarint(fw.out[:0], uint64(count))
+	out = appendUvarint(out, uint64(len(block)))
+	out = append(out, block...)
+	out = append(out, fw.sync[:]...)
+	fw.out = out
+
+	_, err := fw.w.Write(out)
+	return err
+}
+
+// Close flushes the current block. The underlying io.Writer is not closed.
+func (fw *FileWriter) Close() error {
+	return fw.blocks.close()
+}
+
+// FileReader reads values from an object container file. Typical usage is:
+//
+//	for fr.Next() {
+//		err := fr.Decode(&v)
+//		...
+//	}
+//	if err := fr.Err(); err != nil {
+//		...
+//	}
+type FileReader struct {
+	r        decodeReader
+	metadata map[string][]byte
+	sync     [syncSize]byte
+	blocks   blockReader
+}
+
+// NewFileReader reads the header of an object container file from r and
+// returns a FileReader for the file. If r does not implement io.ByteReader,
+// it is buffered.
+func NewFileReader(r io.Reader) (*FileReader, error) {
+	dr, ok := r.(decodeReader)
+	if !ok {
+		dr = bufio.NewReader(r)
+	}
+
+	magic := make([]byte, len(FileMagic))
+	_, err := io.ReadFull(dr, magic)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, FileMagic) {
+		return nil, fmt.Errorf("not an object container file")
+	}
+
+	s, err := DecodeSchema(dr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file schema: %w", err)
+	}
+	fr := &FileReader{
+		r:        dr,
+		metadata: make(map[string][]byte),
+	}
+	fr.blocks = blockReader{
+		plans:     planCache{schema: s},
+		readBlock: fr.readBlock,
+	}
+
+	n, err := ReadUvarint(dr)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < n; i++ {
+		k, err := readBytes(dr)
+		if err != nil {
+			return nil, err
+		}
+		v, err := readBytes(dr)
+		if err != nil {
+			return nil, err
+		}
+		fr.metadata[string(k)] = v
+	}
+
+	_, err = io.ReadFull(dr, fr.sync[:])
+	if err != nil {
+		return nil, err
+	}
+	return fr, nil
+}
+
+// readBytes reads an unsigned variable-size integer length followed by that
+// many bytes from r
+func readBytes(r io.Reader) ([]byte, error) {
+	n, err := ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	return readN(r, n)
+}
+
+// Schema returns the schema used to encode the values in the file (i.e. the
+// writer schema)
+func (fr *FileReader) Schema() Schema {
+	return fr.blocks.plans.schema
+}
+
+// Metadata returns the metadata stored in the file header
+func (fr *FileReader) Metadata() map[string][]byte {
+	return fr.metadata
+}
+
+// Next prepares the next value for reading with Decode. It returns false when
+// there are no more values or an error occurs; use Err to check for errors.
+// Calling Next several times without calling Decode does not skip values.
+func (fr *FileReader) Next() bool {
+	return fr.blocks.next()
+}
+
+// readBlock reads the next block of the file
+func (fr *FileReader) readBlock() (uint64, []byte, error) {
+	count, err := ReadUvarint(fr.r)
+	if err != nil {
+		// io.EOF indicates the end of the file
+		return 0, nil, err
+	}
+	block, err := readBytes(fr.r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var sync [syncSize]byte
+	_, err = io.ReadFull(fr.r, sync[:])
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if sync != fr.sync {
+		return 0, nil, fmt.Errorf("invalid sync marker at end of block")
+	}
+	return count, block, nil
+}
+
+// Decode reads the next value from the file and stores it in i, which must be
+// a non-nil pointer. io.EOF is returned if there are no more values.
+func (fr *FileReader) Decode(i interface{}) error {
+	return fr.DecodeValue(reflect.ValueOf(i))
+}
+
+// DecodeValue reads the next value from the file and stores it in v, which
+// must be a non-nil pointer. io.EOF is returned if there are no more values.
+func (fr *FileReader) DecodeValue(v reflect.Value) error {
+	return fr.blocks.decodeValue(v)
+}
+
+// Err returns the first error encountered by Next, other than io.EOF
+func (fr *FileReader) Err() error {
+	return fr.blocks.errValue()
+}
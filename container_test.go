@@ -0,0 +1,124 @@
+package schemer
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+
+	s, err := SchemaOf(testRecord{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	fw, err := NewFileWriter(&buf, s, map[string][]byte{"source": []byte("test")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.SetBlockSize(100)
+
+	const n = 1000
+	for i := 0; i < n; i++ {
+		v := testRecord{ID: i, Name: "record", Tags: []string{"a", "b"}}
+		if i%2 == 0 {
+			err = fw.Append(&v)
+		} else {
+			err = fw.Append(v)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = fw.Append("not a record"); err == nil {
+		t.Error("expected error appending value of wrong type")
+	}
+	if err = fw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = fw.Append(testRecord{}); err == nil {
+		t.Error("expected error appending to closed file")
+	}
+
+	fr, err := NewFileReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(fr.Metadata()["source"]) != "test" {
+		t.Errorf("unexpected metadata %v", fr.Metadata())
+	}
+	if fr.Schema().GoType() != s.GoType() {
+		t.Errorf("unexpected schema %v", fr.Schema())
+	}
+
+	count := 0
+	for fr.Next() {
+		var v testRecord
+		if err = fr.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		expected := testRecord{ID: count, Name: "record", Tags: []string{"a", "b"}}
+		if !reflect.DeepEqual(v, expected) {
+			t.Fatalf("expected %v; got %v", expected, v)
+		}
+		count++
+	}
+	if err = fr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != n {
+		t.Errorf("expected %d records; got %d", n, count)
+	}
+	var v interface{}
+	if err = fr.Decode(&v); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+}
+
+func TestFileCorrupt(t *testing.T) {
+
+	var buf bytes.Buffer
+	fw, err := NewFileWriter(&buf, &VarIntSchema{Signed: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err = fw.Append(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = fw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// truncated file
+	fr, err := NewFileReader(bytes.NewReader(data[:len(data)-1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for fr.Next() {
+		var i int
+		fr.Decode(&i)
+	}
+	if fr.Err() != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF; got %v", fr.Err())
+	}
+
+	// corrupt sync marker
+	data[len(data)-1]++
+	fr, err = NewFileReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fr.Next() || fr.Err() == nil {
+		t.Error("expected invalid sync marker")
+	}
+
+	if _, err = NewFileReader(bytes.NewReader([]byte("nope"))); err == nil {
+		t.Error("expected error reading invalid magic")
+	}
+}
@@ -0,0 +1,103 @@
+package schemer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// UUID is a 16-byte universally unique identifier for a custom type
+type UUID [16]byte
+
+// String returns the canonical string representation of the UUID
+// (i.e. xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx)
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// CustomTypeGenerator is an interface implemented by schema generators of
+// custom types. A custom type schema is encoded as the CustomTypeByte type
+// byte, followed by the 16-byte UUID of the custom type, followed by the raw
+// schema that describes how values of the custom type are encoded.
+// When Register is called on a CustomTypeGenerator, DecodeSchema will call
+// DecodeCustomSchema when it encounters the generator's UUID. If no generator
+// is registered for a UUID, DecodeSchema returns the raw schema instead, so
+// that values of unknown custom types can still be decoded.
+type CustomTypeGenerator interface {
+	// CustomTypeUUID returns the UUID identifying the custom type
+	CustomTypeUUID() UUID
+
+	// DecodeCustomSchema returns the Schema of the custom type given the
+	// decoded raw schema and the nullable flag of the custom type
+	DecodeCustomSchema(raw Schema, nullable bool) (Schema, error)
+}
+
+// MarshalCustomSchema encodes a custom type schema in a portable binary format.
+// It is intended to be called by the MarshalSchemer method of custom schemas.
+func MarshalCustomSchema(id UUID, nullable bool, raw Schema) ([]byte, error) {
+
+	var schema []byte = []byte{CustomTypeByte}
+
+	// The most signifiant bit indicates whether or not the type is nullable
+	if nullable {
+		schema[0] |= NullMask
+	}
+
+	schema = append(schema, id[:]...)
+
+	m, ok := raw.(Marshaler)
+	if !ok {
+		return nil, fmt.Errorf("raw schema does not implement MarshalSchemer")
+	}
+	rawBytes, err := m.MarshalSchemer()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(schema, rawBytes...), nil
+}
+
+// decodeCustomSchema decodes the remainder of a custom type schema (i.e. the
+// UUID and the raw schema) or a schema reference after its type byte has been
+// read from r
+func (reg *Registry) decodeCustomSchema(r io.Reader, nullable bool) (Schema, error) {
+	var id UUID
+	_, err := io.ReadFull(r, id[:])
+	if err != nil {
+		return nil, err
+	}
+	if ref, ok := schemaRefID(id); ok {
+		return reg.lookupSchemaRef(ref, nullable)
+	}
+
+	raw, err := reg.DecodeSchema(r)
+	if err != nil {
+		return nil, fmt.Errorf("custom type %v: %w", id, err)
+	}
+
+	if sg, ok := reg.generators().customTypes[id]; ok {
+		return sg.DecodeCustomSchema(raw, nullable)
+	}
+
+	// Unknown custom type: fall back to the raw schema
+	if nullable {
+		opt, ok := raw.(interface {
+			SetNullable(bool)
+		})
+		if !ok {
+			return nil, fmt.Errorf("custom type %v: raw schema cannot be nullable", id)
+		}
+		opt.SetNullable(true)
+	}
+	return raw, nil
+}
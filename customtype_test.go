@@ -0,0 +1,194 @@
+package schemer
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// durationSchemaGenerator is a user-defined custom type generator for
+// time.Duration values, which are encoded as signed varints
+type durationSchemaGenerator struct {
+	id UUID
+}
+
+func (sg durationSchemaGenerator) CustomTypeUUID() UUID {
+	return sg.id
+}
+
+func (sg durationSchemaGenerator) DecodeCustomSchema(raw Schema, nullable bool) (Schema, error) {
+	s := &durationSchema{VarIntSchema: VarIntSchema{Signed: true}, id: sg.id}
+	s.SetNullable(nullable)
+	return s, nil
+}
+
+type durationSchema struct {
+	VarIntSchema
+	id UUID
+}
+
+func (s *durationSchema) MarshalSchemer() ([]byte, error) {
+	return MarshalCustomSchema(s.id, s.Nullable(), &VarIntSchema{Signed: true})
+}
+
+func TestUUIDString(t *testing.T) {
+	expected := "a6813d5d-f237-4220-a406-cf9c897da0a9"
+	if dateSchemaUUID.String() != expected {
+		t.Errorf("expected %s; got %s", expected, dateSchemaUUID.String())
+	}
+}
+
+func TestCustomTypeEncoding(t *testing.T) {
+
+	s := &DateSchema{}
+	s.SetNullable(true)
+
+	b, err := s.MarshalSchemer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// type byte + UUID + raw schema (signed varint)
+	if len(b) != 18 {
+		t.Fatalf("unexpected custom type schema length %d", len(b))
+	}
+	if b[0] != CustomTypeByte|NullMask {
+		t.Errorf("unexpected type byte %x", b[0])
+	}
+	if !bytes.Equal(b[1:17], dateSchemaUUID[:]) {
+		t.Error("unexpected custom type UUID")
+	}
+	if b[17] != VarIntByte|1 {
+		t.Errorf("unexpected raw schema %x", b[17])
+	}
+
+	tmp, err := DecodeSchema(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, ok := tmp.(*DateSchema)
+	if !ok || !decoded.Nullable() {
+		t.Fatalf("unexpected decoded schema %#v", tmp)
+	}
+
+	ipSchema, err := SchemaOf(net.IPv4(1, 2, 3, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = ipSchema.(Marshaler).MarshalSchemer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp, err = DecodeSchema(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tmp.(*ipv4Schema); !ok {
+		t.Fatalf("unexpected decoded schema %#v", tmp)
+	}
+}
+
+// make sure the legacy one-byte custom type encodings can still be decoded
+func TestCustomTypeLegacyEncoding(t *testing.T) {
+
+	tests := []struct {
+		b        byte
+		t        reflect.Type
+		nullable bool
+	}{
+		{0x50, reflect.TypeOf(&DateSchema{}), false},
+		{0xD0, reflect.TypeOf(&DateSchema{}), true},
+		{0x60, reflect.TypeOf(&ipv4Schema{}), false},
+		{0xE0, reflect.TypeOf(&ipv4Schema{}), true},
+	}
+
+	for _, test := range tests {
+		s, err := DecodeSchema(bytes.NewReader([]byte{test.b}))
+		if err != nil {
+			t.Fatalf("%x: %v", test.b, err)
+		}
+		if reflect.TypeOf(s) != test.t {
+			t.Errorf("%x: expected %v; got %T", test.b, test.t, s)
+		}
+		if s.(interface{ Nullable() bool }).Nullable() != test.nullable {
+			t.Errorf("%x: unexpected nullable flag", test.b)
+		}
+	}
+
+	// 0b111 0000 was never assigned
+	_, err := DecodeSchema(bytes.NewReader([]byte{0x70}))
+	if err == nil {
+		t.Error("expected error decoding unknown legacy custom type")
+	}
+}
+
+// make sure values of unknown custom types can be decoded using the raw schema
+func TestCustomTypeUnknown(t *testing.T) {
+
+	id := UUID{0x01, 0x02, 0x03}
+	s := &durationSchema{VarIntSchema: VarIntSchema{Signed: true}, id: id}
+	s.SetNullable(true)
+
+	b, err := s.MarshalSchemer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = s.Encode(&buf, 90*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := DecodeSchema(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	varIntSchema, ok := raw.(*VarIntSchema)
+	if !ok || !varIntSchema.Nullable() || !varIntSchema.Signed {
+		t.Fatalf("unexpected raw schema %#v", raw)
+	}
+
+	var decoded int64
+	err = raw.Decode(bytes.NewReader(buf.Bytes()), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Duration(decoded) != 90*time.Second {
+		t.Errorf("unexpected value %d", decoded)
+	}
+}
+
+func TestCustomTypeRegister(t *testing.T) {
+
+	id := UUID{0x04, 0x05, 0x06}
+	err := Register(durationSchemaGenerator{id: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &durationSchema{VarIntSchema: VarIntSchema{Signed: true}, id: id}
+	b, err := s.MarshalSchemer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeSchema(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.(*durationSchema); !ok {
+		t.Fatalf("unexpected decoded schema %#v", decoded)
+	}
+
+	// UUIDs cannot be registered twice
+	err = Register(durationSchemaGenerator{id: id})
+	if err == nil {
+		t.Error("expected error registering duplicate UUID")
+	}
+	err = Register(durationSchemaGenerator{id: dateSchemaUUID})
+	if err == nil {
+		t.Error("expected error registering date UUID")
+	}
+}
@@ -9,8 +9,19 @@ import (
 	"time"
 )
 
-// each custom type has a unique name an a unique UUID
-const dateSchemaUUID byte = 1
+// each custom type has a unique name and a unique UUID
+var dateSchemaUUID = UUID{
+	0xa6, 0x81, 0x3d, 0x5d, 0xf2, 0x37, 0x42, 0x20,
+	0xa4, 0x06, 0xcf, 0x9c, 0x89, 0x7d, 0xa0, 0xa9,
+}
+
+// legacyDateSchemaID is the custom schema identifier that older versions of
+// schemer encoded into bits 4-5 of the type byte
+const legacyDateSchemaID byte = 1
+
+// dateRawSchema describes how dates are encoded (milliseconds since the Unix
+// epoch)
+var dateRawSchema = &VarIntSchema{Signed: true}
 
 type DateSchema struct {
 	SchemaOptions
@@ -21,8 +32,8 @@ type dateSchemaGenerator struct{}
 func (sg dateSchemaGenerator) SchemaOfType(t reflect.Type) (Schema, error) {
 	nullable := false
 
-	// Dereference pointer / interface types
-	for k := t.Kind(); k == reflect.Ptr || k == reflect.Interface; k = t.Kind() {
+	// Dereference pointer types
+	for t.Kind() == reflect.Ptr {
 		t = t.Elem()
 
 		// If we encounter any pointers, then we know this type is nullable
@@ -38,19 +49,30 @@ func (sg dateSchemaGenerator) SchemaOfType(t reflect.Type) (Schema, error) {
 	return nil, nil
 }
 
+func (sg dateSchemaGenerator) CustomTypeUUID() UUID {
+	return dateSchemaUUID
+}
+
+func (sg dateSchemaGenerator) DecodeCustomSchema(raw Schema, nullable bool) (Schema, error) {
+	if vi, ok := raw.(*VarIntSchema); !ok || !vi.Signed {
+		return nil, fmt.Errorf("unsupported raw schema for date")
+	}
+
+	s := &DateSchema{}
+	s.SetNullable(nullable)
+	return s, nil
+}
+
+// DecodeSchema decodes the legacy one-byte date schema
 func (sg dateSchemaGenerator) DecodeSchema(r io.Reader) (Schema, error) {
 
 	tmpBuf := make([]byte, 1)
-	_, err := r.Read(tmpBuf)
+	_, err := io.ReadFull(r, tmpBuf)
 	if err != nil {
 		return nil, err
 	}
 
-	if tmpBuf[0]&CustomMask == CustomMask {
-		if tmpBuf[0]&(dateSchemaUUID<<4) != (dateSchemaUUID << 4) {
-			return nil, nil
-		}
-	} else {
+	if tmpBuf[0]&^NullMask != CustomMask|legacyDateSchemaID<<4 {
 		return nil, nil
 	}
 
@@ -124,21 +146,7 @@ func (s *DateSchema) MarshalJSON() ([]byte, error) {
 
 // Bytes encodes the schema in a portable binary format
 func (s *DateSchema) MarshalSchemer() ([]byte, error) {
-
-	const schemerDateSize byte = 1
-
-	// string schemas are 1 byte long
-	var schema []byte = make([]byte, schemerDateSize)
-
-	schema[0] |= CustomMask
-	schema[0] |= (dateSchemaUUID << 4)
-
-	// The most signifiant bit indicates whether or not the type is nullable
-	if s.Nullable() {
-		schema[0] |= 0x80
-	}
-
-	return schema, nil
+	return MarshalCustomSchema(dateSchemaUUID, s.Nullable(), dateRawSchema)
 }
 
 // Encode uses the schema to write the encoded value of i to the output stream
@@ -148,36 +156,49 @@ func (s *DateSchema) Encode(w io.Writer, i interface{}) error {
 
 // EncodeValue uses the schema to write the encoded value of he output stream
 func (s *DateSchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
 
-	done, err := PreEncode(w, &v, s.Nullable())
+// AppendEncode appends the encoded value of i to dst and returns the extended
+// slice
+func (s *DateSchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
+
+// AppendEncodeValue appends the encoded value of v to dst and returns the
+// extended slice
+func (s *DateSchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
+
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
 	if err != nil || done {
-		return err
+		return dst, err
 	}
 
 	t := v.Type()
-	k := t.Kind()
-
-	if k != reflect.Struct || t.Name() != "Time" || t.PkgPath() != "time" {
-		return fmt.Errorf("DateSchema only supports encoding time.Time values")
+	if t.Kind() != reflect.Struct || t.Name() != "Time" || t.PkgPath() != "time" {
+		return dst, fmt.Errorf("DateSchema only supports encoding time.Time values")
 	}
 
-	// call method UnixNano() on v, which is guarenteed to be a time.Time() due to the above check.
-	// The method returns: "The number of nanoseconds elapsed since January 1, 1970 UTC"
-	// because w are calling the method using reflection, we get back a slice of reflect.Values
-	retValSlice := v.MethodByName("UnixNano").Call(nil)
-	milisecondsToEncode := retValSlice[0].Int() / 1000000
-
-	varIntSchema, err := SchemaOf(milisecondsToEncode)
-	if err != nil {
-		return err
+	var nanos int64
+	switch {
+	case t == timeType && v.CanAddr():
+		// Avoid copying the time.Time to the heap
+		nanos = v.Addr().Interface().(*time.Time).UnixNano()
+	case t == timeType:
+		nanos = v.Interface().(time.Time).UnixNano()
+	default:
+		// call method UnixNano() on v, which is a type named "Time" in package time
+		nanos = v.MethodByName("UnixNano").Call(nil)[0].Int()
 	}
 
-	err = varIntSchema.Encode(w, milisecondsToEncode)
-	if err != nil {
-		return err
+	// Write the number of milliseconds elapsed since January 1, 1970 UTC as a
+	// signed variable-size integer (see dateRawSchema)
+	milisecondsToEncode := nanos / 1000000
+	uintVal := uint64(milisecondsToEncode) << 1
+	if milisecondsToEncode < 0 {
+		uintVal = ^uintVal
 	}
-
-	return nil
+	return appendUvarint(dst, uintVal), nil
 }
 
 // Decode uses the schema to read the next encoded value from the input stream and store it in i
@@ -188,6 +209,11 @@ func (s *DateSchema) Decode(r io.Reader, i interface{}) error {
 	return s.DecodeValue(r, reflect.ValueOf(i))
 }
 
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *DateSchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
 // DecodeValue uses the schema to read the next encoded valuethe input stream and store it in v
 func (s *DateSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 
@@ -214,12 +240,7 @@ func (s *DateSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 
 	var decodedMilliseconds int64
 
-	varIntSchema, err := SchemaOf(decodedMilliseconds)
-	if err != nil {
-		return err
-	}
-
-	err = varIntSchema.Decode(r, &decodedMilliseconds)
+	err = dateRawSchema.Decode(r, &decodedMilliseconds)
 	if err != nil {
 		return err
 	}
@@ -233,9 +254,8 @@ func (s *DateSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 
 	// maybe it makes sense to just return the raw nanoseconds if they are trying
 	// to decode to an integer
-	if k == reflect.Int64 {
-		v.SetInt(nanoSeconds)
-		return nil
+	if isIntKind(k) {
+		return setInt(v, nanoSeconds)
 	}
 
 	if s.weakDecoding {
@@ -182,3 +182,40 @@ func TestCustomDates(t *testing.T) {
 	testRegisteredType2(true, t)
 	testRegisteredType2(false, t)
 }
+
+// TestDateCompatibility checks that dates can be resolved to signed integers
+func TestDateCompatibility(t *testing.T) {
+
+	writer := &DateSchema{}
+	reader := &VarIntSchema{Signed: true}
+	if list := CheckCompatibility(writer, reader); len(list) > 0 {
+		t.Fatalf("unexpected incompatibilities: %v", list)
+	}
+	res, err := NewResolver(writer, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	date := time.Unix(1600000000, 123000000)
+	var buf bytes.Buffer
+	if err = writer.Encode(&buf, date); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := res.Resolve(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != int(date.UnixNano()) {
+		t.Errorf("expected %d; got %v", date.UnixNano(), decoded)
+	}
+
+	// nanoseconds overflow small integers
+	var small int32
+	if err = writer.Decode(bytes.NewReader(buf.Bytes()), &small); err == nil {
+		t.Error("expected overflow error")
+	}
+
+	if list := CheckCompatibility(writer, &VarIntSchema{}); len(list) == 0 {
+		t.Error("expected dates to be incompatible with unsigned integers")
+	}
+}
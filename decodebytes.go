@@ -0,0 +1,231 @@
+package schemer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"unsafe"
+)
+
+// BytesDecoder is an interface implemented by schemas that can decode values
+// directly from a byte slice. All built-in schemas implement BytesDecoder.
+type BytesDecoder interface {
+	// DecodeBytes decodes the encoded value at the start of data, stores it
+	// in i, and returns the number of bytes read from data
+	DecodeBytes(data []byte, i interface{}) (int, error)
+}
+
+// sliceReader is an io.Reader and io.ByteReader that reads from a byte slice.
+// Schemas check for a *sliceReader to read strings without copying them.
+type sliceReader struct {
+	data []byte
+	off  int
+
+	// alias is set if decoded strings may refer to data (see UnsafeDecodeBytes)
+	alias bool
+}
+
+func (r *sliceReader) Read(p []byte) (int, error) {
+	if r.off >= len(r.data) {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, r.data[r.off:])
+	r.off += n
+	return n, nil
+}
+
+func (r *sliceReader) ReadByte() (byte, error) {
+	if r.off >= len(r.data) {
+		return 0, io.EOF
+	}
+	b := r.data[r.off]
+	r.off++
+	return b, nil
+}
+
+// next returns the next n bytes of data without copying them. If fewer than n
+// bytes remain, nothing is read and io.ErrUnexpectedEOF is returned.
+func (r *sliceReader) next(n uint64) ([]byte, error) {
+	if n > uint64(len(r.data)-r.off) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.data[r.off : r.off+int(n)]
+	r.off += int(n)
+	return b, nil
+}
+
+// readString reads a string of n bytes from r. If r is a sliceReader, the
+// length is checked against the remaining input before anything is allocated,
+// and the string refers to the input if aliasing is enabled.
+func readString(r io.Reader, n uint64) (string, error) {
+	if sr, ok := r.(*sliceReader); ok {
+		b, err := sr.next(n)
+		if err != nil || len(b) == 0 {
+			return "", err
+		}
+		if sr.alias {
+			return *(*string)(unsafe.Pointer(&b)), nil
+		}
+		return string(b), nil
+	}
+
+	buf, err := readN(r, n)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// maxPrealloc is the maximum number of bytes allocated for a decoded slice
+// or string before its contents are read, when the length of the remaining
+// input is unknown (i.e. when decoding from a stream)
+const maxPrealloc = 64 << 10
+
+// readN reads the next n bytes from r. Large lengths are read incrementally,
+// so that a corrupt length does not allocate a huge buffer up front.
+func readN(r io.Reader, n uint64) ([]byte, error) {
+	if n <= maxPrealloc {
+		b := make([]byte, int(n))
+		_, err := io.ReadFull(r, b)
+		if err == io.EOF && n > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return b, err
+	}
+	var buf bytes.Buffer
+	copied, err := io.CopyN(&buf, r, int64(n))
+	if err == io.EOF || (err == nil && uint64(copied) != n) {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf.Bytes(), err
+}
+
+// checkLen returns io.ErrUnexpectedEOF if r is a sliceReader with fewer than
+// n values of at least size bytes remaining, so that nothing is allocated for
+// truncated or corrupt input. checked is false if the length could not be
+// checked, because r is not a sliceReader or size is 0.
+func checkLen(r io.Reader, n, size uint64) (checked bool, err error) {
+	sr, ok := r.(*sliceReader)
+	if !ok || size == 0 {
+		return false, nil
+	}
+	if n > uint64(len(sr.data)-sr.off)/size {
+		return true, io.ErrUnexpectedEOF
+	}
+	return true, nil
+}
+
+// minEncodedSize returns a lower bound of the number of bytes of a value
+// encoded with schema s, or 0 if it is unknown
+func minEncodedSize(s Schema) uint64 {
+	if opt, ok := s.(interface{ Nullable() bool }); ok && opt.Nullable() {
+		return 1
+	}
+	switch s := s.(type) {
+	case *Plan:
+		return minEncodedSize(s.schema)
+	case *BoolSchema, *VarIntSchema, *EnumSchema, *VarStringSchema, *VarBytesSchema,
+		*VarArraySchema, *VarObjectSchema, *VariantSchema, *SchemaSchema:
+		return 1
+	case *FixedIntSchema:
+		return uint64(s.Bits / 8)
+	case *FloatSchema:
+		return uint64(s.Bits / 8)
+	case *ComplexSchema:
+		return uint64(s.Bits / 8)
+	case *FixedStringSchema:
+		return uint64(s.Length)
+	case *FixedBytesSchema:
+		return uint64(s.Length)
+	case *FixedArraySchema:
+		if s.Length > 0 {
+			return minEncodedSize(s.Element)
+		}
+	case *FixedObjectSchema:
+		var size uint64
+		for _, f := range s.Fields {
+			if n := minEncodedSize(f.Schema); n > size {
+				size = n
+			}
+		}
+		return size
+	}
+	return 0
+}
+
+// preallocLen returns the number of elements of slice type t that may be
+// allocated before they are read (see maxPrealloc)
+func preallocLen(t reflect.Type) int {
+	size := int(t.Elem().Size())
+	if size == 0 {
+		size = 1
+	}
+	if n := maxPrealloc / size; n > 0 {
+		return n
+	}
+	return 1
+}
+
+// decodeSlice returns a new slice of type t with n elements, each of which is
+// decoded by calling dec. The encoded elements are at least size bytes long
+// (see minEncodedSize). If the length cannot be checked against the remaining
+// input (see checkLen), the slice is grown as elements are decoded.
+func decodeSlice(r io.Reader, t reflect.Type, n, size uint64, dec func(v reflect.Value) error) (reflect.Value, error) {
+	checked, err := checkLen(r, n, size)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if checked || n <= uint64(preallocLen(t)) {
+		v := reflect.MakeSlice(t, int(n), int(n))
+		for i := 0; i < v.Len(); i++ {
+			if err := dec(v.Index(i)); err != nil {
+				return reflect.Value{}, err
+			}
+		}
+		return v, nil
+	}
+
+	v := reflect.MakeSlice(t, 0, preallocLen(t))
+	zero := reflect.Zero(t.Elem())
+	for i := uint64(0); i < n; i++ {
+		v = reflect.Append(v, zero)
+		if err := dec(v.Index(v.Len() - 1)); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	return v, nil
+}
+
+// decodeBytes decodes the encoded value at the start of data using schema s,
+// stores it in v, and returns the number of bytes read from data
+func decodeBytes(s Schema, data []byte, v reflect.Value, alias bool) (int, error) {
+	r := sliceReader{data: data, alias: alias}
+	err := s.DecodeValue(&r, v)
+	return r.off, err
+}
+
+// decodeBytesInterface is decodeBytes for DecodeBytes methods
+func decodeBytesInterface(s Schema, data []byte, i interface{}) (int, error) {
+	if i == nil {
+		return 0, fmt.Errorf("cannot decode to nil destination")
+	}
+	return decodeBytes(s, data, reflect.ValueOf(i), false)
+}
+
+// UnsafeDecodeBytes decodes the encoded value at the start of data using
+// schema s (which may be a Plan), stores it in i, and returns the number of
+// bytes read from data.
+// Unlike DecodeBytes, decoded strings and byte slices are not copied; they
+// refer to the memory of data instead. This avoids an allocation for each
+// string, but data must not be modified for as long as the decoded values are
+// in use.
+func UnsafeDecodeBytes(s Schema, data []byte, i interface{}) (int, error) {
+	if i == nil {
+		return 0, fmt.Errorf("cannot decode to nil destination")
+	}
+	return decodeBytes(s, data, reflect.ValueOf(i), true)
+}
@@ -0,0 +1,234 @@
+package schemer
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+type decodeBytesStruct struct {
+	ID    int64
+	Name  string
+	Code  string `schemer:"[4]"`
+	Tags  []string
+	Score float64
+}
+
+func TestDecodeBytes(t *testing.T) {
+
+	value := decodeBytesStruct{
+		ID:    -5,
+		Name:  "name",
+		Code:  "abcd",
+		Tags:  []string{"x", "yy"},
+		Score: 2.5,
+	}
+	values := []interface{}{
+		value,
+		int16(-300),
+		"a string",
+		[]float32{1, 2},
+		map[string]bool{"t": true},
+		[]interface{}{1, "two"},
+	}
+
+	for _, v := range values {
+		s, err := SchemaOf(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err = s.Encode(&buf, v); err != nil {
+			t.Fatal(err)
+		}
+		encoded := buf.Bytes()
+		// trailing data is not read
+		data := append(append([]byte(nil), encoded...), 0xFF, 0xFF)
+
+		decoded := reflect.New(reflect.TypeOf(v))
+		n, err := s.(BytesDecoder).DecodeBytes(data, decoded.Interface())
+		if err != nil {
+			t.Fatalf("%T: %v", v, err)
+		}
+		if n != len(encoded) {
+			t.Errorf("%T: expected %d bytes read; got %d", v, len(encoded), n)
+		}
+		if !reflect.DeepEqual(decoded.Elem().Interface(), v) {
+			t.Errorf("expected %v; got %v", v, decoded.Elem().Interface())
+		}
+
+		// truncated input
+		for i := 0; i < len(encoded); i++ {
+			decoded = reflect.New(reflect.TypeOf(v))
+			_, err = s.(BytesDecoder).DecodeBytes(encoded[:i], decoded.Interface())
+			if err == nil {
+				t.Errorf("%T: expected error decoding %d of %d bytes", v, i, len(encoded))
+			}
+		}
+	}
+
+	// string lengths are checked before allocating
+	var str string
+	data := appendUvarint(nil, 1<<40)
+	_, err := (&VarStringSchema{}).DecodeBytes(data, &str)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+
+	// array and map lengths are checked before allocating
+	for _, i := range []interface{}{
+		new([]string), new(map[string]bool), new(interface{}),
+	} {
+		s := &VarArraySchema{Element: &VarStringSchema{}}
+		if _, ok := i.(*map[string]bool); ok {
+			_, err = (&VarObjectSchema{Key: &VarStringSchema{}, Value: &BoolSchema{}}).DecodeBytes(data, i)
+		} else {
+			_, err = s.DecodeBytes(data, i)
+		}
+		if err != io.ErrUnexpectedEOF {
+			t.Errorf("%T: expected io.ErrUnexpectedEOF; got %v", i, err)
+		}
+	}
+	if _, err = Unmarshal[[]string](data); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+
+	// arrays of values that may be encoded as no bytes are not checked
+	empty := appendUvarint(nil, 3)
+	var structs []struct{}
+	if _, err = Unmarshal[[]struct{}](empty); err != nil {
+		t.Error(err)
+	}
+	if _, err = (&VarArraySchema{Element: &FixedObjectSchema{}}).DecodeBytes(empty, &structs); err != nil || len(structs) != 3 {
+		t.Errorf("expected 3 elements; got %v, %v", structs, err)
+	}
+
+	// varints are read without allocating
+	r := bytes.NewReader(appendUvarint(nil, 1<<50))
+	allocs := testing.AllocsPerRun(100, func() {
+		r.Seek(0, io.SeekStart)
+		if x, err := ReadUvarint(r); err != nil || x != 1<<50 {
+			t.Fatalf("unexpected result %d, %v", x, err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations; got %v", allocs)
+	}
+}
+
+// TestDecodeLengths checks that corrupt lengths in a stream do not allocate
+// memory for values that are not in the input
+func TestDecodeLengths(t *testing.T) {
+
+	data := appendUvarint(nil, 1<<40)
+	data = append(data, 1, 'x')
+
+	arr := &VarArraySchema{Element: &VarStringSchema{}}
+	floats := &VarArraySchema{Element: &FloatSchema{Bits: 64}}
+	obj := &VarObjectSchema{Key: &VarStringSchema{}, Value: &BoolSchema{}}
+	for _, test := range []struct {
+		s Schema
+		i interface{}
+	}{
+		{arr, new([]string)},
+		{floats, new([]float64)},
+		{obj, new(map[string]bool)},
+		{&VarBytesSchema{}, new([]byte)},
+		{&VarStringSchema{}, new(string)},
+	} {
+		p, err := Compile(test.s, reflect.TypeOf(test.i).Elem())
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := NewResolver(test.s, test.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range []Schema{test.s, p} {
+			if err = s.Decode(bytes.NewReader(data), test.i); err == nil {
+				t.Errorf("%T %T: expected error", s, test.i)
+			}
+		}
+		if _, err = res.Resolve(bytes.NewReader(data)); err == nil {
+			t.Errorf("resolver %T: expected error", test.i)
+		}
+		_, err = res.Resolve(&sliceReader{data: data})
+		if err != io.ErrUnexpectedEOF {
+			t.Errorf("resolver %T: expected io.ErrUnexpectedEOF; got %v", test.i, err)
+		}
+	}
+
+	// values larger than the preallocated size are decoded from streams
+	value := make([]float64, 3*maxPrealloc/8+1)
+	strs := make([]string, preallocLen(reflect.TypeOf([]string{}))+1)
+	for i := range value {
+		value[i] = float64(i)
+	}
+	for _, v := range []interface{}{value, strs, bytes.Repeat([]byte{'x'}, 2*maxPrealloc)} {
+		s, err := SchemaOf(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := s.(Appender).AppendEncode(nil, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := reflect.New(reflect.TypeOf(v))
+		if err = s.Decode(bytes.NewReader(b), decoded.Interface()); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded.Elem().Interface(), v) {
+			t.Errorf("%T: decoded value does not match", v)
+		}
+	}
+}
+
+func TestUnsafeDecodeBytes(t *testing.T) {
+
+	value := decodeBytesStruct{Name: "name", Code: "abcd", Tags: []string{"x", "yy"}}
+	s, err := SchemaOf(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Compile(s, reflect.TypeOf(value))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := p.AppendEncode(nil, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []Schema{s, p} {
+		var safe, aliased decodeBytesStruct
+		if _, err = s.(BytesDecoder).DecodeBytes(data, &safe); err != nil {
+			t.Fatal(err)
+		}
+		n, err := UnsafeDecodeBytes(s, data, &aliased)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(data) || !reflect.DeepEqual(aliased, value) {
+			t.Fatalf("%T: unexpected value %+v", s, aliased)
+		}
+
+		// aliased strings refer to data
+		i := bytes.Index(data, []byte("name"))
+		data[i] = 'N'
+		if aliased.Name != "Name" || safe.Name != "name" {
+			t.Errorf("%T: unexpected strings %q and %q", s, aliased.Name, safe.Name)
+		}
+		data[i] = 'n'
+
+		safeAllocs := testing.AllocsPerRun(100, func() {
+			s.(BytesDecoder).DecodeBytes(data, &safe)
+		})
+		unsafeAllocs := testing.AllocsPerRun(100, func() {
+			UnsafeDecodeBytes(s, data, &aliased)
+		})
+		if unsafeAllocs >= safeAllocs {
+			t.Errorf("%T: expected fewer allocations; got %v and %v", s, unsafeAllocs, safeAllocs)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 	"strconv"
 )
 
@@ -50,17 +51,30 @@ func (s EnumSchema) MarshalSchemer() ([]byte, error) {
 	}
 
 	// write all the enumerated values as part of the schema...
+	// Note: values are written in ascending order, so the encoded schema is
+	// deterministic. This is the same encoding as a map[uint]string.
 	var buf bytes.Buffer
 
-	mapSchema := VarObjectSchema{
-		Key:   &VarIntSchema{Signed: false},
-		Value: &VarStringSchema{},
+	keys := make([]int, 0, len(s.Values))
+	for k := range s.Values {
+		keys = append(keys, k)
 	}
+	sort.Ints(keys)
 
-	err := mapSchema.Encode(&buf, s.Values)
+	err := WriteUvarint(&buf, uint64(len(keys)))
 	if err != nil {
 		return nil, err
 	}
+	keySchema := &VarIntSchema{Signed: false}
+	valueSchema := &VarStringSchema{}
+	for _, k := range keys {
+		if err = keySchema.Encode(&buf, k); err != nil {
+			return nil, err
+		}
+		if err = valueSchema.Encode(&buf, s.Values[k]); err != nil {
+			return nil, err
+		}
+	}
 
 	schema = append(schema, buf.Bytes()...)
 
@@ -75,11 +89,21 @@ func (s *EnumSchema) Encode(w io.Writer, i interface{}) error {
 
 // EncodeValue uses the schema to write the encoded value of v to the output streamtream
 func (s *EnumSchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
+
+// AppendEncode appends the encoded value of i to dst and returns the extended slice
+func (s *EnumSchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
+
+// AppendEncodeValue appends the encoded value of v to dst and returns the extended slice
+func (s *EnumSchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
 	varIntSchema := VarIntSchema{
 		Signed:        false,
 		SchemaOptions: SchemaOptions{nullable: s.Nullable()},
 	}
-	return varIntSchema.EncodeValue(w, v)
+	return varIntSchema.AppendEncodeValue(dst, v)
 }
 
 // Decode uses the schema to read the next encoded value from the input stream and store it in i
@@ -90,6 +114,11 @@ func (s *EnumSchema) Decode(r io.Reader, i interface{}) error {
 	return s.DecodeValue(r, reflect.ValueOf(i))
 }
 
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *EnumSchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
 // DecodeValue uses the schema to read the next encoded value from the input stream and store it in v
 func (s *EnumSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 
@@ -103,33 +103,48 @@ func (s *FixedArraySchema) Encode(w io.Writer, i interface{}) error {
 
 // EncodeValue uses the schema to write the encoded value of v to the output stream
 func (s *FixedArraySchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
+
+// AppendEncode appends the encoded value of i to dst and returns the extended slice
+func (s *FixedArraySchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
+
+// AppendEncodeValue appends the encoded value of v to dst and returns the extended slice
+func (s *FixedArraySchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
 
 	// just double check the schema they are using
 	if !s.Valid() {
-		return fmt.Errorf("cannot encode using invalid FixedArraySchema schema")
+		return dst, fmt.Errorf("cannot encode using invalid FixedArraySchema schema")
 	}
 
-	done, err := PreEncode(w, &v, s.Nullable())
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
 	if err != nil || done {
-		return err
+		return dst, err
 	}
 
-	t := v.Type()
-	k := t.Kind()
-
-	if k != reflect.Array {
-		return fmt.Errorf("FixedArraySchema can only encode fixed length arrays")
+	if v.Kind() != reflect.Array {
+		return dst, fmt.Errorf("FixedArraySchema can only encode fixed length arrays")
 	}
 
 	if s.Length != v.Len() {
-		return fmt.Errorf("source array size does not match schema size")
+		return dst, fmt.Errorf("source array size does not match schema size")
+	}
+
+	// arrays of fixed-width numbers are copied in one pass
+	if l, ok := bulkLayoutOf(s.Element, v.Type().Elem()); ok {
+		return appendBulk(dst, v, l), nil
 	}
 
 	for i := 0; i < v.Len(); i++ {
-		s.Element.Encode(w, v.Index(i).Interface())
+		dst, err = appendEncodeValue(s.Element, dst, v.Index(i))
+		if err != nil {
+			return dst, err
+		}
 	}
 
-	return nil
+	return dst, nil
 }
 
 // Decode uses the schema to read the next encoded value from the input stream and store it in i
@@ -140,6 +155,11 @@ func (s *FixedArraySchema) Decode(r io.Reader, i interface{}) error {
 	return s.DecodeValue(r, reflect.ValueOf(i))
 }
 
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *FixedArraySchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
 // DecodeValue uses the schema to read the next encoded value from the input stream and store it in v
 func (s *FixedArraySchema) DecodeValue(r io.Reader, v reflect.Value) error {
 
@@ -172,6 +192,11 @@ func (s *FixedArraySchema) DecodeValue(r io.Reader, v reflect.Value) error {
 		return fmt.Errorf("source array size does not match schema size")
 	}
 
+	// arrays of fixed-width numbers are read in one pass
+	if l, ok := bulkLayoutOf(s.Element, t.Elem()); ok && v.CanAddr() {
+		return readBulk(r, v, l)
+	}
+
 	for i := 0; i < s.Length; i++ {
 		err := s.Element.DecodeValue(r, v.Index(i))
 		if err != nil {
@@ -9,7 +9,7 @@ import (
 
 func TestDecodeFixedLenArray1(t *testing.T) {
 
-	var testarray [10]byte = [10]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	var testarray [10]int16 = [10]int16{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
 
 	s, err := SchemaOf(testarray)
 	if err != nil {
@@ -0,0 +1,141 @@
+package schemer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// FixedBytesSchema is a Schema for fixed-length binary strings (i.e. [N]byte
+// values). Values are encoded as exactly Length raw bytes.
+type FixedBytesSchema struct {
+	SchemaOptions
+	Length int
+}
+
+func (s *FixedBytesSchema) GoType() reflect.Type {
+	var t byte
+	retval := reflect.ArrayOf(s.Length, reflect.TypeOf(t))
+
+	if s.Nullable() {
+		retval = reflect.PtrTo(retval)
+	}
+
+	return retval
+}
+
+func (s *FixedBytesSchema) Valid() bool {
+	return s.Length >= 0
+}
+
+func (s *FixedBytesSchema) MarshalJSON() ([]byte, error) {
+
+	if !s.Valid() {
+		return nil, fmt.Errorf("invalid FixedBytesSchema")
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"type":     "bytes",
+		"length":   s.Length,
+		"nullable": s.Nullable(),
+	})
+}
+
+// MarshalSchemer encodes the schema in a portable binary format
+func (s *FixedBytesSchema) MarshalSchemer() ([]byte, error) {
+
+	// bytes schemas are 1 byte long
+	var schema []byte = []byte{FixedBytesByte}
+
+	// The most signifiant bit indicates whether or not the type is nullable
+	if s.Nullable() {
+		schema[0] |= NullMask
+	}
+
+	// encode fixed length as a varint
+	buf := make([]byte, binary.MaxVarintLen64)
+	varIntByteLength := binary.PutVarint(buf, int64(s.Length))
+
+	schema = append(schema, buf[0:varIntByteLength]...)
+
+	return schema, nil
+}
+
+// Encode uses the schema to write the encoded value of i to the output stream
+func (s *FixedBytesSchema) Encode(w io.Writer, i interface{}) error {
+	return s.EncodeValue(w, reflect.ValueOf(i))
+}
+
+// EncodeValue uses the schema to write the encoded value of v to the output stream
+func (s *FixedBytesSchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
+
+// AppendEncode appends the encoded value of i to dst and returns the extended slice
+func (s *FixedBytesSchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
+
+// AppendEncodeValue appends the encoded value of v to dst and returns the
+// extended slice. v may be a byte slice, a byte array, or a string of exactly
+// Length bytes.
+func (s *FixedBytesSchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
+
+	// just double check the schema they are using
+	if !s.Valid() {
+		return dst, fmt.Errorf("cannot encode using invalid FixedBytesSchema")
+	}
+
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
+	if err != nil || done {
+		return dst, err
+	}
+
+	if !isBytesKind(v) {
+		return dst, fmt.Errorf("FixedBytesSchema only supports encoding byte slices, byte arrays, and strings")
+	}
+	if v.Len() != s.Length {
+		return dst, fmt.Errorf("binary string length %d does not match schema length %d", v.Len(), s.Length)
+	}
+
+	return appendBytesValue(dst, v), nil
+}
+
+// Decode uses the schema to read the next encoded value from the input stream and store it in i
+func (s *FixedBytesSchema) Decode(r io.Reader, i interface{}) error {
+	if i == nil {
+		return fmt.Errorf("cannot decode to nil destination")
+	}
+	return s.DecodeValue(r, reflect.ValueOf(i))
+}
+
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *FixedBytesSchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
+// DecodeValue uses the schema to read the next encoded value from the input
+// stream and store it in v. Values can be decoded to byte slices, byte arrays
+// of the same length, and strings (with weak decoding).
+func (s *FixedBytesSchema) DecodeValue(r io.Reader, v reflect.Value) error {
+
+	// just double check the schema they are using
+	if !s.Valid() {
+		return fmt.Errorf("cannot decode using invalid FixedBytesSchema")
+	}
+
+	done, err := PreDecode(r, &v, s.Nullable())
+	if err != nil || done {
+		return err
+	}
+
+	if v.Kind() == reflect.Interface {
+		v.Set(reflect.New(s.GoType()))
+
+		v = v.Elem().Elem()
+	}
+
+	return decodeBytesValue(r, uint64(s.Length), v, s.WeakDecoding())
+}
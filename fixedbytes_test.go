@@ -0,0 +1,169 @@
+package schemer
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// TestFixedBytes1 checks that byte arrays use a FixedBytesSchema and that the
+// schema survives the binary and JSON encodings
+func TestFixedBytes1(t *testing.T) {
+
+	s, err := SchemaOf([16]byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs, ok := s.(*FixedBytesSchema); !ok || fs.Length != 16 {
+		t.Fatalf("expected *FixedBytesSchema of length 16; got %#v", s)
+	}
+
+	schema := &FixedBytesSchema{SchemaOptions: SchemaOptions{nullable: true}, Length: 300}
+	b, err := schema.MarshalSchemer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp, err := DecodeSchema(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tmp, schema) {
+		t.Errorf("expected %#v; got %#v", schema, tmp)
+	}
+
+	j, err := schema.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp, err = DecodeSchemaJSON(bytes.NewReader(j))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tmp, schema) {
+		t.Errorf("expected %#v; got %#v", schema, tmp)
+	}
+}
+
+func TestFixedBytes2(t *testing.T) {
+
+	schema := &FixedBytesSchema{Length: 4}
+	value := [4]byte{0xDE, 0xAD, 0xBE, 0xEF}
+
+	// unaddressable arrays, byte slices, and strings can all be encoded
+	var buf bytes.Buffer
+	for _, v := range []interface{}{value, &value, value[:], string(value[:])} {
+		buf.Reset()
+		if err := schema.Encode(&buf, v); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), value[:]) {
+			t.Fatalf("%T: expected %v; got %v", v, value, buf.Bytes())
+		}
+	}
+
+	if err := schema.Encode(&buf, []byte{1, 2, 3}); err == nil {
+		t.Error("expected error encoding value of the wrong length")
+	}
+
+	var decoded [4]byte
+	if err := schema.Decode(bytes.NewReader(value[:]), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != value {
+		t.Errorf("expected %v; got %v", value, decoded)
+	}
+
+	var slice []byte
+	if err := schema.Decode(bytes.NewReader(value[:]), &slice); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(slice, value[:]) {
+		t.Errorf("expected %v; got %v", value, slice)
+	}
+
+	var i interface{}
+	if err := schema.Decode(bytes.NewReader(value[:]), &i); err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := i.(*[4]byte); !ok || *p != value {
+		t.Errorf("expected %v; got %#v", value, i)
+	}
+
+	var str string
+	if err := schema.Decode(bytes.NewReader(value[:]), &str); err == nil {
+		t.Error("expected error decoding to string without weak decoding")
+	}
+	weak := &FixedBytesSchema{SchemaOptions: SchemaOptions{weakDecoding: true}, Length: 4}
+	if err := weak.Decode(bytes.NewReader(value[:]), &str); err != nil {
+		t.Fatal(err)
+	}
+	if str != string(value[:]) {
+		t.Errorf("expected %q; got %q", value, str)
+	}
+}
+
+func TestFixedBytes3(t *testing.T) {
+
+	type id struct {
+		ID   [16]byte
+		Hash *[4]byte
+	}
+	value := id{ID: [16]byte{1, 2, 3}}
+	s, err := SchemaOf(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Compile(s, reflect.TypeOf(value))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []Schema{s, p} {
+		for _, v := range []id{value, {Hash: &[4]byte{9, 8, 7, 6}}} {
+			var buf bytes.Buffer
+			if err := s.Encode(&buf, v); err != nil {
+				t.Fatal(err)
+			}
+			// 16 bytes, a null byte, and possibly 4 more bytes
+			expectedLen := 17
+			if v.Hash != nil {
+				expectedLen += 4
+			}
+			if buf.Len() != expectedLen {
+				t.Errorf("%T: expected %d bytes; got %d", s, expectedLen, buf.Len())
+			}
+
+			var decoded id
+			if err := s.Decode(&buf, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, v) {
+				t.Errorf("%T: expected %v; got %v", s, v, decoded)
+			}
+		}
+	}
+}
+
+func TestFixedBytesCompatibility(t *testing.T) {
+
+	w := &FixedBytesSchema{Length: 4}
+	for _, r := range []Schema{
+		&FixedBytesSchema{Length: 4},
+		&VarBytesSchema{},
+		&FixedArraySchema{Length: 4, Element: &VarIntSchema{}},
+		&FixedBytesSchema{Length: 5},
+	} {
+		list := CheckCompatibility(w, r)
+		expectErr := reflect.DeepEqual(r, &FixedBytesSchema{Length: 5}) ||
+			reflect.TypeOf(r) == reflect.TypeOf(&FixedArraySchema{})
+		if expectErr != (len(list) > 0) {
+			t.Errorf("%#v: unexpected incompatibilities: %v", r, list)
+		}
+	}
+
+	// fixed-length arrays of integers can be read as binary strings
+	list := CheckCompatibility(&FixedArraySchema{Length: 4, Element: &VarIntSchema{}}, w)
+	if len(list) > 0 {
+		t.Errorf("unexpected incompatibilities: %v", list)
+	}
+}
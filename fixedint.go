@@ -2,7 +2,6 @@ package schemer
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"reflect"
@@ -112,51 +111,36 @@ func (s *FixedIntSchema) MarshalJSON() ([]byte, error) {
 	})
 }
 
-func writeUint(w io.Writer, v uint64, s *FixedIntSchema) error {
+// appendUint appends the fixed-size encoding of v to dst
+func appendUint(dst []byte, v uint64, s *FixedIntSchema) ([]byte, error) {
 	switch s.Bits {
 	case 8:
-		n, err := w.Write([]byte{byte(v)})
-		if err == nil && n != 1 {
-			err = errors.New("unexpected number of bytes written")
-		}
-		return err
+		return append(dst, byte(v)), nil
 	case 16:
-		n, err := w.Write([]byte{
+		return append(dst,
 			byte(v),
-			byte(v >> 8),
-		})
-		if err == nil && n != 2 {
-			err = errors.New("unexpected number of bytes written")
-		}
-		return err
+			byte(v>>8),
+		), nil
 	case 32:
-		n, err := w.Write([]byte{
+		return append(dst,
 			byte(v),
-			byte(v >> 8),
-			byte(v >> 16),
-			byte(v >> 24),
-		})
-		if err == nil && n != 4 {
-			err = errors.New("unexpected number of bytes written")
-		}
-		return err
+			byte(v>>8),
+			byte(v>>16),
+			byte(v>>24),
+		), nil
 	case 64:
-		n, err := w.Write([]byte{
+		return append(dst,
 			byte(v),
-			byte(v >> 8),
-			byte(v >> 16),
-			byte(v >> 24),
-			byte(v >> 32),
-			byte(v >> 40),
-			byte(v >> 48),
-			byte(v >> 56),
-		})
-		if err == nil && n != 8 {
-			err = errors.New("unexpected number of bytes written")
-		}
-		return err
+			byte(v>>8),
+			byte(v>>16),
+			byte(v>>24),
+			byte(v>>32),
+			byte(v>>40),
+			byte(v>>48),
+			byte(v>>56),
+		), nil
 	default:
-		return fmt.Errorf("invalid fixed integer size: %d bits", s.Bits)
+		return dst, fmt.Errorf("invalid fixed integer size: %d bits", s.Bits)
 	}
 }
 
@@ -171,20 +155,18 @@ func readUint(r io.Reader, s *FixedIntSchema) (uint64, error) {
 	}
 
 	// Convert bytes to int value
+	// Note: values are not sign-extended; signed values are zig-zag encoded
 	switch s.Bits {
 	case 8:
-		var raw int8 = int8(buf[0])
-		return uint64(raw), nil
+		return uint64(buf[0]), nil
 	case 16:
-		var raw int16 = int16(buf[0]) |
-			int16(buf[1])<<8
-		return uint64(raw), nil
+		return uint64(buf[0]) |
+			uint64(buf[1])<<8, nil
 	case 32:
-		var raw int32 = int32(buf[0]) |
-			int32(buf[1])<<8 |
-			int32(buf[2])<<16 |
-			int32(buf[3])<<24
-		return uint64(raw), nil
+		return uint64(buf[0]) |
+			uint64(buf[1])<<8 |
+			uint64(buf[2])<<16 |
+			uint64(buf[3])<<24, nil
 	case 64:
 		return uint64(buf[0]) |
 			uint64(buf[1])<<8 |
@@ -249,77 +231,62 @@ func (s *FixedIntSchema) Encode(w io.Writer, i interface{}) error {
 
 // EncodeValue uses the schema to write the encoded value of v to the output stream
 func (s *FixedIntSchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
+
+// AppendEncode appends the encoded value of i to dst and returns the extended slice
+func (s *FixedIntSchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
+
+// AppendEncodeValue appends the encoded value of v to dst and returns the extended slice
+func (s *FixedIntSchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
 
 	// just double check the schema they are using
 	if !s.Valid() {
-		return fmt.Errorf("cannot encode using invalid FixedIntSchema schema")
+		return dst, fmt.Errorf("cannot encode using invalid FixedIntSchema schema")
 	}
 
-	done, err := PreEncode(w, &v, s.Nullable())
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
 	if err != nil || done {
-		return err
+		return dst, err
 	}
 
-	t := v.Type()
-	k := t.Kind()
-
+	k := v.Kind()
 	if !checkType(s, k) {
-		return fmt.Errorf("encode failure; value to be encoded does not match FixedIntSchema schema")
+		return dst, fmt.Errorf("encode failure; value to be encoded does not match FixedIntSchema schema")
 	}
 
-	switch k {
-	case reflect.Int:
-		fallthrough
-	case reflect.Int8:
-		fallthrough
-	case reflect.Int16:
-		fallthrough
-	case reflect.Int32:
-		fallthrough
-	case reflect.Int64:
+	// Check integer range
+	start := int64(0)
+	end := uint64(0xFFFFFFFFFFFFFFFF) // 8 bytes
+	end >>= (64 - s.Bits)
+	if s.Signed {
+		end /= 2
+		start = -int64(end) - 1
+	}
+
+	switch {
+	case isIntKind(k):
 		intVal := v.Int()
-		// Check integer range
-		start := int64(0)
-		end := uint64(0xFFFFFFFFFFFFFFFF) // 8 bytes
-		end >>= (64 - s.Bits)
-		if s.Signed {
-			end /= 2
-			start = -int64(end) - 1
-		}
 		if intVal > int64(end) || intVal < start {
-			return fmt.Errorf("integer out of range %d to %d", start, end)
+			return dst, fmt.Errorf("integer out of range %d to %d", start, end)
 		}
 		// Write value
 		uintVal := uint64(intVal) << 1
 		if intVal < 0 {
 			uintVal = ^uintVal
 		}
-		return writeUint(w, uintVal, s)
-	case reflect.Uint:
-		fallthrough
-	case reflect.Uint8:
-		fallthrough
-	case reflect.Uint16:
-		fallthrough
-	case reflect.Uint32:
-		fallthrough
-	case reflect.Uint64:
+		return appendUint(dst, uintVal, s)
+	case isUintKind(k):
 		uintVal := v.Uint()
-		// Check integer range
-		start := int64(0)
-		end := uint64(0xFFFFFFFFFFFFFFFF) // 8 bytes
-		end >>= (64 - s.Bits)
-		if s.Signed {
-			end /= 2
-			start = -int64(end) - 1
-		}
 		if uintVal > end {
-			return fmt.Errorf("integer out of range %d to %d", start, end)
+			return dst, fmt.Errorf("integer out of range %d to %d", start, end)
 		}
 		// Write value
-		return writeUint(w, uintVal, s)
+		return appendUint(dst, uintVal, s)
 	}
-	return nil
+	return dst, nil
 }
 
 // Decode uses the schema to read the next encoded value from the input stream and store it in i
@@ -330,6 +297,11 @@ func (s *FixedIntSchema) Decode(r io.Reader, i interface{}) error {
 	return s.DecodeValue(r, reflect.ValueOf(i))
 }
 
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *FixedIntSchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
 // DecodeValue uses the schema to read the next encoded value from the input stream and store it in v
 func (s *FixedIntSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 
@@ -3,6 +3,7 @@ package schemer
 import (
 	"bytes"
 	"fmt"
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -796,3 +797,32 @@ func TestFixedIntSchema6(t *testing.T) {
 	}
 
 }
+
+// TestFixedIntLargeValues checks that values using the most significant bit
+// of the encoded integer are decoded correctly
+func TestFixedIntLargeValues(t *testing.T) {
+	tests := []struct {
+		s     *FixedIntSchema
+		value interface{}
+	}{
+		{&FixedIntSchema{Bits: 8}, uint8(255)},
+		{&FixedIntSchema{Bits: 16}, uint16(0xFFFF)},
+		{&FixedIntSchema{Signed: true, Bits: 8}, int8(127)},
+		{&FixedIntSchema{Signed: true, Bits: 8}, int8(-128)},
+		{&FixedIntSchema{Signed: true, Bits: 32}, int32(1 << 30)},
+		{&FixedIntSchema{Signed: true, Bits: 32}, int32(-1 << 31)},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := test.s.Encode(&buf, test.value); err != nil {
+			t.Fatal(err)
+		}
+		decoded := reflect.New(reflect.TypeOf(test.value))
+		if err := test.s.Decode(&buf, decoded.Interface()); err != nil {
+			t.Fatalf("%T(%v): %v", test.value, test.value, err)
+		}
+		if decoded.Elem().Interface() != test.value {
+			t.Errorf("expected %v; got %v", test.value, decoded.Elem())
+		}
+	}
+}
@@ -7,11 +7,20 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"strings"
+	"unicode"
 )
 
 type ObjectField struct {
 	Aliases []string
 	Schema  Schema
+
+	// Default is the value of the field when decoding a value whose schema has
+	// no matching field. Default is converted to the destination type using
+	// weak decoding; a nil Default means that the field has no default value.
+	// Note: default values are included in the JSON representation of a
+	// schema, but not in its binary representation.
+	Default interface{}
 }
 
 type FixedObjectSchema struct {
@@ -19,13 +28,30 @@ type FixedObjectSchema struct {
 	Fields []ObjectField
 }
 
+// GoType returns a struct type with one exported field per object field.
+// Field names are derived from the first alias of each field; the aliases are
+// kept in a struct tag when they differ from the field name, so that values of
+// the struct type can be decoded by alias.
 func (s *FixedObjectSchema) GoType() reflect.Type {
 	var fields []reflect.StructField = make([]reflect.StructField, len(s.Fields))
+	used := make(map[string]bool, len(s.Fields))
 
 	for i := 0; i < len(s.Fields); i++ {
+		var alias string
+		if len(s.Fields[i].Aliases) > 0 {
+			alias = s.Fields[i].Aliases[0]
+		}
+		base := goFieldName(alias)
+		name := base
+		for j := 2; used[name]; j++ {
+			name = fmt.Sprintf("%s_%d", base, j)
+		}
+		used[name] = true
+
 		fields[i] = reflect.StructField{
-			Name: s.Fields[i].Aliases[0],
-			Type: s.Fields[i].Schema.GoType()}
+			Name: name,
+			Type: s.Fields[i].Schema.GoType(),
+			Tag:  aliasTag(name, s.Fields[i].Aliases)}
 	}
 
 	retval := reflect.StructOf(fields)
@@ -37,6 +63,44 @@ func (s *FixedObjectSchema) GoType() reflect.Type {
 	return retval
 }
 
+// goFieldName returns an exported Go identifier for the field alias: invalid
+// characters are replaced by underscores, and the first letter is capitalized
+// (or "X" is prepended if the alias does not start with a letter)
+func goFieldName(alias string) string {
+	name := []rune(alias)
+	for i, c := range name {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' {
+			name[i] = '_'
+		}
+	}
+	if len(name) > 0 && unicode.IsLetter(name[0]) {
+		name[0] = unicode.ToUpper(name[0])
+	}
+	if len(name) == 0 || !unicode.IsUpper(name[0]) {
+		name = append([]rune{'X'}, name...)
+	}
+	return string(name)
+}
+
+// aliasTag returns the struct tag listing the aliases of a struct field named
+// name. Aliases that cannot be written in a struct tag are left out, and no
+// tag is needed if name is the only alias.
+func aliasTag(name string, aliases []string) reflect.StructTag {
+	var list []string
+	for _, alias := range aliases {
+		if structTagAliasRegex.MatchString(alias) {
+			list = append(list, alias)
+		}
+	}
+	switch {
+	case len(list) == 0 || len(list) == 1 && list[0] == name:
+		return ""
+	case len(list) == 1:
+		return reflect.StructTag(fmt.Sprintf(`%s:"%s"`, StructTagName, list[0]))
+	}
+	return reflect.StructTag(fmt.Sprintf(`%s:"[%s]"`, StructTagName, strings.Join(list, ",")))
+}
+
 func (s *FixedObjectSchema) MarshalJSON() ([]byte, error) {
 	tmpMap := make(map[string]interface{}, 4)
 	tmpMap["type"] = "object"
@@ -61,6 +125,9 @@ func (s *FixedObjectSchema) MarshalJSON() ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
+		if s.Fields[i].Default != nil {
+			fields["default"] = s.Fields[i].Default
+		}
 		fieldMap = append(fieldMap, fields)
 	}
 
@@ -98,7 +165,7 @@ func (s *FixedObjectSchema) MarshalSchemer() ([]byte, error) {
 		// now write each field alias
 		for i := 0; i < len(f.Aliases); i++ {
 			s := f.Aliases[i]
-			varLenStringSchema, _ := SchemaOf(s)
+			varLenStringSchema := &VarStringSchema{}
 			var buf bytes.Buffer
 			varLenStringSchema.Encode(&buf, s)
 			schemaBytes = append(schemaBytes, buf.Bytes()...)
@@ -126,54 +193,34 @@ func (s *FixedObjectSchema) Encode(w io.Writer, i interface{}) error {
 
 // EncodeValue uses the schema to write the encoded value of v to the output stream
 func (s *FixedObjectSchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
 
-	done, err := PreEncode(w, &v, s.Nullable())
+// AppendEncode appends the encoded value of i to dst and returns the extended slice
+func (s *FixedObjectSchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
+
+// AppendEncodeValue appends the encoded value of v to dst and returns the extended slice
+func (s *FixedObjectSchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
+
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
 	if err != nil || done {
-		return err
+		return dst, err
 	}
 
-	t := v.Type()
-	k := t.Kind()
-
-	if k != reflect.Struct {
-		return fmt.Errorf("fixedObjectSchema can only encode structs")
+	if v.Kind() != reflect.Struct {
+		return dst, fmt.Errorf("fixedObjectSchema can only encode structs")
 	}
 	// loop through all the schemas in this object
 	// and encode each field
 	for i := 0; i < len(s.Fields); i++ {
-		err := s.Fields[i].Schema.EncodeValue(w, v.Field(i))
+		dst, err = appendEncodeValue(s.Fields[i].Schema, dst, v.Field(i))
 		if err != nil {
-			return err
+			return dst, err
 		}
 	}
-	return nil
-}
-
-// findDestinationField returns the name of the field in a destination struct (v) that should be populated
-// based on the name of the field from the source structure.
-func (s *FixedObjectSchema) findDestinationField(sourceFieldAlias string, v reflect.Value) string {
-
-	// see if there is a place in the destination struct that matches the alias passed in...
-	for i := 0; i < v.Type().NumField(); i++ {
-		fieldName := v.Type().Field(i).Name
-
-		if sourceFieldAlias == fieldName {
-			return sourceFieldAlias
-		}
-
-		// parse the tags on this field, to see if any aliases are present...
-		tagOpts := ParseStructTag(v.Type().Field(i).Tag.Get(StructTagName))
-
-		// if any of the aliases on this destination field match sourceFieldAlias, then we have a match!
-		for j := 0; j < len(tagOpts.FieldAliases); j++ {
-			if sourceFieldAlias == tagOpts.FieldAliases[j] {
-				return fieldName
-			}
-
-		}
-
-	}
-	return ""
+	return dst, nil
 }
 
 // Decode uses the schema to read the next encoded value from the input stream and store it in v
@@ -184,6 +231,11 @@ func (s *FixedObjectSchema) Decode(r io.Reader, i interface{}) error {
 	return s.DecodeValue(r, reflect.ValueOf(i))
 }
 
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *FixedObjectSchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
 // DecodeValue uses the schema to read the next encoded value from the input stream and store it in v
 func (s *FixedObjectSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 
@@ -195,40 +247,58 @@ func (s *FixedObjectSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 	t := v.Type()
 	k := t.Kind()
 
+	// the fields of the schema's own Go type are decoded in order
+	ownType := false
 	if k == reflect.Interface {
 		v.Set(reflect.New(s.GoType()))
 
 		v = v.Elem().Elem()
 		t = v.Type()
 		k = t.Kind()
+		ownType = true
 	}
 
 	if k != reflect.Struct {
 		return fmt.Errorf("FixedObjectSchema can only decode to structures")
 	}
 
-	var stringToMatch string
+	// keep track of the destination fields that are populated, so that
+	// default values can be applied to the others
+	populated := make(map[string]bool, len(s.Fields))
 
 	// loop through all the potential source fields
 	// and see if there is anywhere we can put them
 	for i := 0; i < len(s.Fields); i++ {
-		found := false
-
-		for j := 0; j < len(s.Fields[i].Aliases); j++ {
-			stringToMatch = s.Fields[i].Aliases[j]
-			structFieldToPopulate := s.findDestinationField(stringToMatch, v)
-
-			if structFieldToPopulate != "" {
-				found = true
-
-				err := s.Fields[i].Schema.DecodeValue(r, v.FieldByName(structFieldToPopulate))
-				if err != nil {
-					return err
-				}
+		if ownType {
+			populated[t.Field(i).Name] = true
+			err := s.Fields[i].Schema.DecodeValue(r, v.Field(i))
+			if err != nil {
+				return err
 			}
+			continue
+		}
+
+		// every destination field that matches one of the aliases is
+		// populated, but the encoded value is only read from r once
+		indexes := findStructFields(s.Fields[i].Aliases, t)
+		for _, index := range indexes {
+			populated[t.Field(index).Name] = true
 		}
 
-		if !found {
+		if len(indexes) == 1 {
+			err := s.Fields[i].Schema.DecodeValue(r, v.Field(indexes[0]))
+			if err != nil {
+				return err
+			}
+		} else if len(indexes) > 1 {
+			schema := s.Fields[i].Schema
+			err := decodeRepeated(newDecodeReader(r), len(indexes), func(r decodeReader, j int) error {
+				return schema.DecodeValue(r, v.Field(indexes[j]))
+			})
+			if err != nil {
+				return err
+			}
+		} else {
 			// otherwise, there is just an extra field from the source struct that we cannot match
 			// in the destination struct
 			// since there is no where to put the field, we just need to skip it
@@ -240,5 +310,48 @@ func (s *FixedObjectSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 			}
 		}
 	}
+
+	// apply default values from struct tags to fields that were not populated
+	fields := structFieldsOf(t)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if populated[f.Name] || len(f.PkgPath) != 0 {
+			continue
+		}
+		if fields[i].tag.DefaultSet {
+			err := fields[i].setDefault(v.Field(i))
+			if err != nil {
+				return fmt.Errorf("struct field %v: %w", f.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setDefault stores the default value def in v. def is converted to the type
+// of v according to the type compatibility rules, using weak decoding. The
+// defaults in struct tags are converted once per struct type (see
+// structFieldsOf).
+func setDefault(v reflect.Value, def interface{}) error {
+	s, err := SchemaOf(def)
+	if err != nil {
+		return fmt.Errorf("default value: %w", err)
+	}
+	s = CloneSchema(s)
+	if opt, ok := s.(interface {
+		SetWeakDecoding(bool)
+	}); ok {
+		opt.SetWeakDecoding(true)
+	}
+
+	var buf bytes.Buffer
+	err = s.Encode(&buf, def)
+	if err != nil {
+		return fmt.Errorf("default value: %w", err)
+	}
+	err = s.DecodeValue(&buf, v)
+	if err != nil {
+		return fmt.Errorf("default value: %w", err)
+	}
 	return nil
 }
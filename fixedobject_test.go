@@ -2,6 +2,7 @@ package schemer
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"log"
 	"reflect"
@@ -225,3 +226,101 @@ func TestDecodeFixedObject6(t *testing.T) {
 	}
 
 }
+
+// TestDecodeFixedObjectDefaults tests that fields missing from the encoded
+// data are set to their default values
+func TestDecodeFixedObjectDefaults(t *testing.T) {
+
+	type SourceStruct struct {
+		Name string
+	}
+
+	type DestinationStruct struct {
+		Name  string
+		Age   int     `schemer:"[Age,Years],default=18"`
+		Admin *bool   `schemer:",weak,default=true"`
+		Score float64 `schemer:",default=1.5"`
+	}
+
+	tag := ParseStructTag("[A,B],weak,default=5")
+	if !tag.WeakDecoding || !tag.DefaultSet || tag.Default != "5" {
+		t.Errorf("unexpected struct tag %+v", tag)
+	}
+
+	writerSchema, err := SchemaOf(SourceStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	readerSchema, err := SchemaOf(DestinationStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readerSchema.(*FixedObjectSchema).Fields[1].Default != 18 {
+		t.Error("expected default value in schema")
+	}
+
+	var encodedData bytes.Buffer
+	err = writerSchema.Encode(&encodedData, SourceStruct{Name: "ben"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(name string, dest DestinationStruct) {
+		if dest.Name != "ben" || dest.Age != 18 || dest.Admin == nil ||
+			!*dest.Admin || dest.Score != 1.5 {
+			t.Errorf("%s: unexpected defaults %+v", name, dest)
+		}
+	}
+
+	var dest DestinationStruct
+	err = writerSchema.Decode(bytes.NewReader(encodedData.Bytes()), &dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("Decode", dest)
+
+	// the default values are converted once, but each decoded value gets
+	// its own pointer
+	var again DestinationStruct
+	err = writerSchema.Decode(bytes.NewReader(encodedData.Bytes()), &again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("Decode again", again)
+	if again.Admin == dest.Admin {
+		t.Error("expected default pointers not to be shared")
+	}
+
+	plan, err := Compile(writerSchema, reflect.TypeOf(dest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest = DestinationStruct{}
+	err = plan.Decode(bytes.NewReader(encodedData.Bytes()), &dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("Plan", dest)
+
+	// defaults survive a JSON round trip and are used by resolvers
+	b, err := json.Marshal(readerSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedSchema, err := DecodeSchemaJSON(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := NewResolver(writerSchema, decodedSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := res.Resolve(bytes.NewReader(encodedData.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := reflect.ValueOf(decoded)
+	if v.FieldByName("Age").Int() != 18 || v.FieldByName("Score").Float() != 1.5 {
+		t.Errorf("unexpected resolved defaults %+v", decoded)
+	}
+}
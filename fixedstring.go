@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type FixedStringSchema struct {
@@ -74,37 +75,41 @@ func (s *FixedStringSchema) Encode(w io.Writer, i interface{}) error {
 
 // EncodeValue uses the schema to write the encoded value of v to the output stream
 func (s *FixedStringSchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
+
+// AppendEncode appends the encoded value of i to dst and returns the extended slice
+func (s *FixedStringSchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
+
+// AppendEncodeValue appends the encoded value of v to dst and returns the extended slice
+func (s *FixedStringSchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
 
 	// just double check the schema they are using
 	if !s.Valid() {
-		return fmt.Errorf("cannot encode using invalid FixedStringSchema")
+		return dst, fmt.Errorf("cannot encode using invalid FixedStringSchema")
 	}
 
-	done, err := PreEncode(w, &v, s.Nullable())
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
 	if err != nil || done {
-		return err
+		return dst, err
 	}
 
-	t := v.Type()
-	k := t.Kind()
-
-	if k != reflect.String {
-		return fmt.Errorf("FixedStringSchema only supports encoding string values")
+	if v.Kind() != reflect.String {
+		return dst, fmt.Errorf("FixedStringSchema only supports encoding string values")
 	}
 
-	var stringToEncode string = v.String()
-
-	// if we are encoding a fixed len string, we just need to pad it
-
-	formatString := "%-" + strconv.Itoa(s.Length) + "v"
-	stringToEncode = fmt.Sprintf(formatString, stringToEncode)
+	stringToEncode := v.String()
+	dst = append(dst, stringToEncode...)
 
-	_, err = w.Write([]byte(stringToEncode))
-	if err != nil {
-		return err
+	// if we are encoding a fixed len string, we just need to pad it with
+	// spaces (i.e. fmt's "%-Nv" verb)
+	for n := utf8.RuneCountInString(stringToEncode); n < s.Length; n++ {
+		dst = append(dst, ' ')
 	}
 
-	return nil
+	return dst, nil
 }
 
 // Decode uses the schema to read the next encoded value from the input stream and store it in i
@@ -115,6 +120,11 @@ func (s *FixedStringSchema) Decode(r io.Reader, i interface{}) error {
 	return s.DecodeValue(r, reflect.ValueOf(i))
 }
 
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *FixedStringSchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
 // DecodeValue uses the schema to read the next encoded value from the input stream and store it in v
 func (s *FixedStringSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 
@@ -139,17 +149,12 @@ func (s *FixedStringSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 		k = t.Kind()
 	}
 
-	var decodedString string
-
-	buf := make([]byte, s.Length)
-	_, err = io.ReadAtLeast(r, buf, s.Length)
+	// when we return as a string, we will return it with the padding intact
+	decodedString, err := readString(r, uint64(s.Length))
 	if err != nil {
 		return err
 	}
 
-	// when we return as a string, we will return it with the padding intact
-	decodedString = string(buf)
-
 	// but for conversions, having a trimmed up string will make things easier
 	trimString := strings.Trim(decodedString, " ")
 
@@ -0,0 +1,12 @@
+package schemer
+
+// testRecord is a record shared by the tests of message envelopes, streams,
+// containers, and the other framing formats
+type testRecord struct {
+	ID     int
+	Name   string
+	Color  int
+	Tags   []string
+	Active bool
+	Inner  *embeddedStruct
+}
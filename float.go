@@ -2,7 +2,6 @@ package schemer
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -76,22 +75,30 @@ func (s *FloatSchema) Encode(w io.Writer, i interface{}) error {
 
 // EncodeValue uses the schema to write the encoded value of v to the output stream
 func (s *FloatSchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
+
+// AppendEncode appends the encoded value of i to dst and returns the extended slice
+func (s *FloatSchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
+
+// AppendEncodeValue appends the encoded value of v to dst and returns the extended slice
+func (s *FloatSchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
 
 	// just double check the schema they are using
 	if !s.Valid() {
-		return fmt.Errorf("cannot encode using invalid StringSchema schema")
+		return dst, fmt.Errorf("cannot encode using invalid StringSchema schema")
 	}
 
-	done, err := PreEncode(w, &v, s.Nullable())
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
 	if err != nil || done {
-		return err
+		return dst, err
 	}
 
-	t := v.Type()
-	k := t.Kind()
-
+	k := v.Kind()
 	if k != reflect.Float32 && k != reflect.Float64 {
-		return fmt.Errorf("FloatSchema only supports encoding float32 and float64 values")
+		return dst, fmt.Errorf("FloatSchema only supports encoding float32 and float64 values")
 	}
 	floatV := v.Float()
 
@@ -99,43 +106,32 @@ func (s *FloatSchema) EncodeValue(w io.Writer, v reflect.Value) error {
 	switch s.Bits {
 	case 32:
 		if k == reflect.Float64 {
-			return fmt.Errorf("32bit FloatSchema schema cannot encode 64 bit values")
+			return dst, fmt.Errorf("32bit FloatSchema schema cannot encode 64 bit values")
 		}
 		i := math.Float32bits(float32(floatV))
 
-		n, err := w.Write([]byte{
+		return append(dst,
 			byte(i),
-			byte(i >> 8),
-			byte(i >> 16),
-			byte(i >> 24),
-		})
-		if err == nil && n != 4 {
-			err = errors.New("unexpected number of bytes written")
-		}
-		return err
+			byte(i>>8),
+			byte(i>>16),
+			byte(i>>24),
+		), nil
 
 	case 64:
 		i := math.Float64bits(floatV)
 
-		n, err := w.Write([]byte{
+		return append(dst,
 			byte(i),
-			byte(i >> 8),
-			byte(i >> 16),
-			byte(i >> 24),
-			byte(i >> 32),
-			byte(i >> 40),
-			byte(i >> 48),
-			byte(i >> 56),
-		})
-		if err == nil && n != 8 {
-			return errors.New("unexpected number of bytes written")
-		}
-		return err
-
-	default:
-		// error
+			byte(i>>8),
+			byte(i>>16),
+			byte(i>>24),
+			byte(i>>32),
+			byte(i>>40),
+			byte(i>>48),
+			byte(i>>56),
+		), nil
 	}
-	return nil
+	return dst, nil
 }
 
 // Decode uses the schema to read the next encoded value from the input stream and store it in i
@@ -146,6 +142,11 @@ func (s *FloatSchema) Decode(r io.Reader, i interface{}) error {
 	return s.DecodeValue(r, reflect.ValueOf(i))
 }
 
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *FloatSchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
 // DecodeValue uses the schema to read the next encoded value from the input stream and store it in v
 func (s *FloatSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 
@@ -0,0 +1,154 @@
+package schemer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// DefaultMaxFrameSize is the default maximum size of the encoded value in a
+// frame written by a FrameWriter or read by a FrameReader
+const DefaultMaxFrameSize = 4 << 20
+
+// ErrFrameTooLarge is returned (wrapped) when a frame exceeds the maximum
+// frame size
+var ErrFrameTooLarge = errors.New("frame exceeds maximum size")
+
+// FrameWriter writes values encoded with a Schema to a connection or other
+// stream, each preceded by its length in bytes as an unsigned variable-size
+// integer (see WriteUvarint)
+type FrameWriter struct {
+	w       io.Writer
+	plans   planCache
+	maxSize int
+
+	payload []byte
+	out     []byte
+}
+
+// NewFrameWriter returns a FrameWriter that writes values encoded with schema
+// s to w
+func NewFrameWriter(w io.Writer, s Schema) *FrameWriter {
+	return &FrameWriter{
+		w:       w,
+		plans:   planCache{schema: s},
+		maxSize: DefaultMaxFrameSize,
+	}
+}
+
+// SetMaxFrameSize sets the maximum size of the encoded value in a frame
+func (fw *FrameWriter) SetMaxFrameSize(n int) {
+	fw.maxSize = n
+}
+
+// Encode writes a frame containing the encoded value of i using a single call
+// to Write
+func (fw *FrameWriter) Encode(i interface{}) error {
+	return fw.EncodeValue(reflect.ValueOf(i))
+}
+
+// EncodeValue writes a frame containing the encoded value of v using a single
+// call to Write
+func (fw *FrameWriter) EncodeValue(v reflect.Value) error {
+	payload, err := fw.plans.appendValue(fw.payload[:0], v)
+	fw.payload = payload
+	if err != nil {
+		return err
+	}
+	if len(payload) > fw.maxSize {
+		return fmt.Errorf("%w: %d > %d bytes", ErrFrameTooLarge, len(payload), fw.maxSize)
+	}
+
+	out := appendWriter{fw.out[:0]}
+	err = WriteUvarint(&out, uint64(len(payload)))
+	if err != nil {
+		return err
+	}
+	out.Write(payload)
+	fw.out = out.b
+
+	_, err = fw.w.Write(out.b)
+	return err
+}
+
+// FrameReader reads frames written by a FrameWriter. It never reads beyond the
+// end of the frame being decoded, so the underlying connection can be used for
+// other purposes between frames.
+type FrameReader struct {
+	r       io.Reader
+	plans   planCache
+	maxSize int
+	err     error // ErrFrameTooLarge, once a frame is too large
+
+	buf     []byte
+	payload bytes.Reader
+}
+
+// NewFrameReader returns a FrameReader that reads values encoded with schema s
+// from r
+func NewFrameReader(r io.Reader, s Schema) *FrameReader {
+	return &FrameReader{
+		r:       r,
+		plans:   planCache{schema: s},
+		maxSize: DefaultMaxFrameSize,
+	}
+}
+
+// SetMaxFrameSize sets the maximum size of the encoded value in a frame.
+// ErrFrameTooLarge is returned for larger frames (see ReadFrame).
+func (fr *FrameReader) SetMaxFrameSize(n int) {
+	fr.maxSize = n
+}
+
+// ReadFrame reads the next frame and returns the encoded value. The returned
+// slice is only valid until the next call to ReadFrame, Decode, or
+// DecodeValue.
+// If the frame is larger than the maximum frame size, ErrFrameTooLarge is
+// returned without reading the frame, since its length cannot be trusted. The
+// stream cannot be used after that, and all later calls return the same error.
+func (fr *FrameReader) ReadFrame() ([]byte, error) {
+	if fr.err != nil {
+		return nil, fr.err
+	}
+	n, err := ReadUvarint(fr.r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(fr.maxSize) {
+		fr.err = fmt.Errorf("%w: %d > %d bytes", ErrFrameTooLarge, n, fr.maxSize)
+		return nil, fr.err
+	}
+
+	if uint64(cap(fr.buf)) < n {
+		fr.buf = make([]byte, n)
+	}
+	buf := fr.buf[:n]
+	_, err = io.ReadFull(fr.r, buf)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Decode reads the next frame and stores its value in i, which must be a
+// non-nil pointer
+func (fr *FrameReader) Decode(i interface{}) error {
+	return fr.DecodeValue(reflect.ValueOf(i))
+}
+
+// DecodeValue reads the next frame and stores its value in v, which must be a
+// non-nil pointer
+func (fr *FrameReader) DecodeValue(v reflect.Value) error {
+	buf, err := fr.ReadFrame()
+	if err != nil {
+		return err
+	}
+
+	fr.payload.Reset(buf)
+	return fr.plans.decodeAll(&fr.payload, v, "frame")
+}
@@ -0,0 +1,105 @@
+package schemer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestFrame(t *testing.T) {
+
+	s, err := SchemaOf(testRecord{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := []testRecord{
+		{ID: 1, Name: "one", Tags: []string{"a"}},
+		{ID: 2, Name: "two", Tags: []string{"b"}},
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		fw := NewFrameWriter(client, s)
+		for _, v := range values {
+			if err := fw.Encode(v); err != nil {
+				t.Error(err)
+			}
+		}
+		// raw bytes that follow the frames
+		client.Write([]byte("raw"))
+	}()
+
+	fr := NewFrameReader(server, s)
+	for _, expected := range values {
+		var v testRecord
+		if err = fr.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, expected) {
+			t.Errorf("expected %v; got %v", expected, v)
+		}
+	}
+
+	// the reader must not have consumed the raw bytes
+	raw := make([]byte, 3)
+	if _, err = io.ReadFull(server, raw); err != nil || string(raw) != "raw" {
+		t.Errorf("unexpected raw bytes %q, %v", raw, err)
+	}
+}
+
+func TestFrameTooLarge(t *testing.T) {
+
+	s := &VarStringSchema{}
+	var buf bytes.Buffer
+	fw := NewFrameWriter(&buf, s)
+	for _, str := range []string{"a long string", "short"} {
+		if err := fw.Encode(str); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fw.SetMaxFrameSize(4)
+	if err := fw.Encode("too long"); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("expected ErrFrameTooLarge; got %v", err)
+	}
+
+	// large frames are not read, and the stream cannot be used afterwards
+	fr := NewFrameReader(&buf, s)
+	fr.SetMaxFrameSize(8)
+	var str string
+	for i := 0; i < 2; i++ {
+		if err := fr.Decode(&str); !errors.Is(err, ErrFrameTooLarge) {
+			t.Errorf("expected ErrFrameTooLarge; got %v", err)
+		}
+	}
+
+	// huge lengths are rejected without reading the rest of the stream
+	for _, n := range []uint64{1 << 40, 1<<63 + 1} {
+		r := bytes.NewReader(append(appendUvarint(nil, n), make([]byte, 16)...))
+		fr = NewFrameReader(r, s)
+		if err := fr.Decode(&str); !errors.Is(err, ErrFrameTooLarge) {
+			t.Errorf("%d: expected ErrFrameTooLarge; got %v", n, err)
+		}
+		if r.Len() != 16 {
+			t.Errorf("%d: expected frame to be left unread; %d bytes remain", n, r.Len())
+		}
+	}
+
+	// invalid lengths
+	for _, data := range [][]byte{
+		bytes.Repeat([]byte{0xFF}, 12),
+		{0x80},
+		{0x05, 'a'},
+	} {
+		fr = NewFrameReader(bytes.NewReader(data), s)
+		if err := fr.Decode(&str); err == nil || err == io.EOF {
+			t.Errorf("expected error decoding %v; got %v", data, err)
+		}
+	}
+}
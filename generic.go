@@ -0,0 +1,156 @@
+package schemer
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// Codec is a type-safe encoder and decoder for values of Go type T. The
+// Schema and the compiled Plan for T are built once when the Codec is
+// created, and encoding buffers are reused. A Codec is safe for concurrent
+// use by multiple goroutines.
+type Codec[T any] struct {
+	plan *Plan
+	bufs sync.Pool
+}
+
+// NewCodec returns a Codec for Go type T using the Schema returned by
+// SchemaOfType
+func NewCodec[T any]() (*Codec[T], error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	s, err := SchemaOfType(t)
+	if err != nil {
+		return nil, err
+	}
+	return NewSchemaCodec[T](s)
+}
+
+// NewSchemaCodec returns a Codec that encodes values of Go type T using
+// schema s and decodes values encoded with schema s into values of Go type T
+// (i.e. s may be a writer schema read from a stream).
+func NewSchemaCodec[T any](s Schema) (*Codec[T], error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	p, err := Compile(s, t)
+	if err != nil {
+		return nil, err
+	}
+	c := &Codec[T]{plan: p}
+	c.bufs.New = func() interface{} {
+		b := make([]byte, 0, 512)
+		return &b
+	}
+	return c, nil
+}
+
+// Schema returns the Schema used by c
+func (c *Codec[T]) Schema() Schema {
+	return c.plan.schema
+}
+
+// Plan returns the compiled Plan used by c
+func (c *Codec[T]) Plan() *Plan {
+	return c.plan
+}
+
+// appendValue appends the encoded value of v to b
+func (c *Codec[T]) appendValue(b []byte, v *T) ([]byte, error) {
+	return c.plan.appendValue(b, reflect.ValueOf(v).Elem())
+}
+
+// AppendEncode appends the encoded value of v to dst and returns the extended
+// slice
+func (c *Codec[T]) AppendEncode(dst []byte, v T) ([]byte, error) {
+	return c.appendValue(dst, &v)
+}
+
+// Encode writes the encoded value of v to w using a single call to Write
+func (c *Codec[T]) Encode(w io.Writer, v T) error {
+	bp := c.bufs.Get().(*[]byte)
+	b, err := c.appendValue((*bp)[:0], &v)
+	if err == nil {
+		_, err = w.Write(b)
+	}
+	if cap(b) <= maxPooledBufSize {
+		*bp = b
+		c.bufs.Put(bp)
+	}
+	return err
+}
+
+// Decode reads the next encoded value from r and returns it
+func (c *Codec[T]) Decode(r io.Reader) (T, error) {
+	var v T
+	err := c.plan.dec(newDecodeReader(r), reflect.ValueOf(&v).Elem())
+	return v, err
+}
+
+// Marshal returns the encoded value of v
+func (c *Codec[T]) Marshal(v T) ([]byte, error) {
+	bp := c.bufs.Get().(*[]byte)
+	b, err := c.appendValue((*bp)[:0], &v)
+	var out []byte
+	if err == nil {
+		out = make([]byte, len(b))
+		copy(out, b)
+	}
+	if cap(b) <= maxPooledBufSize {
+		*bp = b
+		c.bufs.Put(bp)
+	}
+	return out, err
+}
+
+// Unmarshal decodes the encoded value in b and returns it. An error is
+// returned if b contains more than one encoded value.
+func (c *Codec[T]) Unmarshal(b []byte) (T, error) {
+	var v T
+	r := sliceReader{data: b}
+	err := c.plan.dec(&r, reflect.ValueOf(&v).Elem())
+	if n := len(b) - r.off; err == nil && n > 0 {
+		err = fmt.Errorf("unmarshal: %d bytes of trailing data", n)
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return v, err
+}
+
+// codecOf returns the Codec for Go type T using DefaultRegistry. Codecs are
+// cached along with the registry's schemas, so they are rebuilt when a schema
+// generator is registered or unregistered.
+func codecOf[T any]() (*Codec[T], error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	gens := DefaultRegistry.generators()
+	if c, ok := gens.codecs.Load(t); ok {
+		return c.(*Codec[T]), nil
+	}
+	c, err := NewCodec[T]()
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := gens.codecs.LoadOrStore(t, c)
+	return actual.(*Codec[T]), nil
+}
+
+// Marshal returns the encoded value of v using the Schema returned by
+// SchemaOfType for Go type T
+func Marshal[T any](v T) ([]byte, error) {
+	c, err := codecOf[T]()
+	if err != nil {
+		return nil, err
+	}
+	return c.Marshal(v)
+}
+
+// Unmarshal decodes the encoded value in b, which was encoded using the Schema
+// returned by SchemaOfType for Go type T (i.e. by Marshal)
+func Unmarshal[T any](b []byte) (T, error) {
+	c, err := codecOf[T]()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return c.Unmarshal(b)
+}
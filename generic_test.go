@@ -0,0 +1,139 @@
+package schemer
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestCodec(t *testing.T) {
+
+	c, err := NewCodec[planStruct]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < 4; i++ {
+		value := newPlanStruct(i)
+
+		var expected bytes.Buffer
+		if err = c.Schema().Encode(&expected, value); err != nil {
+			t.Fatal(err)
+		}
+		b, err := c.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, expected.Bytes()) {
+			t.Fatalf("%d: codec encoding differs from schema encoding", i)
+		}
+		if err = c.Encode(&buf, value); err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := c.Unmarshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded.Created = value.Created
+		if !reflect.DeepEqual(decoded, value) {
+			t.Errorf("%d: expected %#v; got %#v", i, value, decoded)
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		decoded, err := c.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.ID != int64(i)*-1000 {
+			t.Errorf("%d: unexpected ID %d", i, decoded.ID)
+		}
+	}
+	if _, err = c.Decode(&buf); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+
+	b, _ := c.Marshal(newPlanStruct(0))
+	if _, err = c.Unmarshal(append(b, 0)); err == nil {
+		t.Error("expected error for trailing data")
+	}
+	if _, err = c.Unmarshal(b[:len(b)-1]); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+
+	b, err := Marshal([]string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	strs, err := Unmarshal[[]string](b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(strs, []string{"a", "b"}) {
+		t.Errorf("unexpected value %v", strs)
+	}
+
+	// pointers are nullable
+	b, err = Marshal[*int](nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Unmarshal[*int](b)
+	if err != nil || p != nil {
+		t.Errorf("unexpected result %v, %v", p, err)
+	}
+
+	// the cached Codec is reused
+	c1, _ := codecOf[[]string]()
+	c2, _ := codecOf[[]string]()
+	if c1 != c2 {
+		t.Error("expected cached codec")
+	}
+
+	// decoding a value of another type fails
+	if _, err = Unmarshal[float64](b); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func BenchmarkCodecMarshal(b *testing.B) {
+
+	c, err := NewCodec[planStruct]()
+	if err != nil {
+		b.Fatal(err)
+	}
+	value := newPlanStruct(2)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err = c.Marshal(value); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCodecAppendEncode(t *testing.T) {
+
+	c, err := NewCodec[planStruct]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := newPlanStruct(1)
+	expected, err := c.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := c.AppendEncode([]byte{1, 2}, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b[:2], []byte{1, 2}) || !bytes.Equal(b[2:], expected) {
+		t.Errorf("unexpected encoding %v", b)
+	}
+}
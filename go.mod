@@ -1,3 +1,3 @@
 module github.com/bminer/schemer
 
-go 1.16
+go 1.18
@@ -9,8 +9,21 @@ import (
 	"strings"
 )
 
-// each custom type has a unique name an a unique UUID
-const ipV4SchemaUUID byte = 2
+// each custom type has a unique name and a unique UUID
+var ipV4SchemaUUID = UUID{
+	0x72, 0xfd, 0x73, 0xc9, 0xfd, 0xd8, 0x49, 0x33,
+	0x8c, 0x70, 0x38, 0xa2, 0xc8, 0x60, 0x1b, 0xfa,
+}
+
+// legacyIPv4SchemaID is the custom schema identifier that older versions of
+// schemer encoded into bits 4-5 of the type byte
+const legacyIPv4SchemaID byte = 2
+
+// ipv4RawSchema describes how IPv4 addresses are encoded (an array of 4 bytes)
+var ipv4RawSchema = &FixedArraySchema{
+	Length:  4,
+	Element: &VarIntSchema{Signed: false},
+}
 
 type ipv4Schema struct {
 	SchemaOptions
@@ -21,8 +34,8 @@ type ipv4SchemaGenerator struct{}
 func (sg ipv4SchemaGenerator) SchemaOfType(t reflect.Type) (Schema, error) {
 	nullable := false
 
-	// Dereference pointer / interface types
-	for k := t.Kind(); k == reflect.Ptr || k == reflect.Interface; k = t.Kind() {
+	// Dereference pointer types
+	for t.Kind() == reflect.Ptr {
 		t = t.Elem()
 
 		// If we encounter any pointers, then we know this type is nullable
@@ -38,19 +51,30 @@ func (sg ipv4SchemaGenerator) SchemaOfType(t reflect.Type) (Schema, error) {
 	return nil, nil
 }
 
+func (sg ipv4SchemaGenerator) CustomTypeUUID() UUID {
+	return ipV4SchemaUUID
+}
+
+func (sg ipv4SchemaGenerator) DecodeCustomSchema(raw Schema, nullable bool) (Schema, error) {
+	if fa, ok := raw.(*FixedArraySchema); !ok || fa.Length != 4 {
+		return nil, fmt.Errorf("unsupported raw schema for ipv4")
+	}
+
+	s := &ipv4Schema{}
+	s.SetNullable(nullable)
+	return s, nil
+}
+
+// DecodeSchema decodes the legacy one-byte ipv4 schema
 func (sg ipv4SchemaGenerator) DecodeSchema(r io.Reader) (Schema, error) {
 
 	tmpBuf := make([]byte, 1)
-	_, err := r.Read(tmpBuf)
+	_, err := io.ReadFull(r, tmpBuf)
 	if err != nil {
 		return nil, err
 	}
 
-	if tmpBuf[0]&CustomMask == CustomMask {
-		if tmpBuf[0]&(ipV4SchemaUUID<<4) != (ipV4SchemaUUID << 4) {
-			return nil, nil
-		}
-	} else {
+	if tmpBuf[0]&^NullMask != CustomMask|legacyIPv4SchemaID<<4 {
 		return nil, nil
 	}
 
@@ -132,21 +156,7 @@ func (s *ipv4Schema) MarshalJSON() ([]byte, error) {
 
 // Bytes encodes the schema in a portable binary format
 func (s *ipv4Schema) MarshalSchemer() ([]byte, error) {
-
-	const schemerDateSize byte = 1
-
-	// string schemas are 1 byte long
-	var schema []byte = make([]byte, schemerDateSize)
-
-	schema[0] |= CustomMask
-	schema[0] |= (ipV4SchemaUUID << 4)
-
-	// The most signifiant bit indicates whether or not the type is nullable
-	if s.Nullable() {
-		schema[0] |= 0x80
-	}
-
-	return schema, nil
+	return MarshalCustomSchema(ipV4SchemaUUID, s.Nullable(), ipv4RawSchema)
 }
 
 // Encode uses the schema to write the encoded value of i to the output stream
@@ -156,38 +166,34 @@ func (s *ipv4Schema) Encode(w io.Writer, i interface{}) error {
 
 // EncodeValue uses the schema to write the encoded value of he output stream
 func (s *ipv4Schema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
 
-	done, err := PreEncode(w, &v, s.Nullable())
-	if err != nil || done {
-		return err
-	}
+// AppendEncode appends the encoded value of i to dst and returns the extended
+// slice
+func (s *ipv4Schema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
 
-	t := v.Type()
-	k := t.Kind()
+// AppendEncodeValue appends the encoded value of v to dst and returns the
+// extended slice
+func (s *ipv4Schema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
 
-	if k != reflect.Slice || t.Name() != "IP" || t.PkgPath() != "net" {
-		return fmt.Errorf("ipSchema only supports encoding net.IP values")
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
+	if err != nil || done {
+		return dst, err
 	}
 
-	var bytesToEncode [4]byte
-
-	bytesToEncode[0] = byte(v.Index(0).Uint())
-	bytesToEncode[1] = byte(v.Index(1).Uint())
-	bytesToEncode[2] = byte(v.Index(2).Uint())
-	bytesToEncode[3] = byte(v.Index(3).Uint())
-
-	fixedArraySchema, err := SchemaOf(bytesToEncode)
-	if err != nil {
-		return err
+	t := v.Type()
+	if t.Kind() != reflect.Slice || t.Name() != "IP" || t.PkgPath() != "net" {
+		return dst, fmt.Errorf("ipSchema only supports encoding net.IP values")
 	}
 
-	err = fixedArraySchema.Encode(w, bytesToEncode)
-
-	if err != nil {
-		return err
+	// Note: ipv4RawSchema is an array of 4 unsigned variable-size integers
+	for i := 0; i < 4; i++ {
+		dst = appendUvarint(dst, uint64(byte(v.Index(i).Uint())))
 	}
-
-	return nil
+	return dst, nil
 }
 
 // Decode uses the schema to read the next encoded value from the input stream and store it in i
@@ -198,6 +204,11 @@ func (s *ipv4Schema) Decode(r io.Reader, i interface{}) error {
 	return s.DecodeValue(r, reflect.ValueOf(i))
 }
 
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *ipv4Schema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
 // DecodeValue uses the schema to read the next encoded valuethe input stream and store it in v
 func (s *ipv4Schema) DecodeValue(r io.Reader, v reflect.Value) error {
 
@@ -224,11 +235,7 @@ func (s *ipv4Schema) DecodeValue(r io.Reader, v reflect.Value) error {
 
 	var decodedBytes [4]byte
 
-	fixedArraySchema, err := SchemaOf(decodedBytes)
-	if err != nil {
-		return err
-	}
-	err = fixedArraySchema.Decode(r, &decodedBytes)
+	err = ipv4RawSchema.Decode(r, &decodedBytes)
 
 	if err != nil {
 		return err
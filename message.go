@@ -0,0 +1,194 @@
+package schemer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Messages are self-describing values: each message starts with a header that
+// identifies the schema used to encode the value, followed by the encoded
+// value. See encoding-format.md for details.
+const (
+	MessageMagic   = 0xD3 // first byte of every message
+	MessageVersion = 1    // current version of the message format
+)
+
+// SchemaReference indicates how the writer schema of a message is identified
+type SchemaReference byte
+
+const (
+	// InlineSchema messages include the entire schema (see MarshalSchemer)
+	InlineSchema SchemaReference = iota
+	// FingerprintSchema messages include the 64-bit fingerprint of the schema
+	// (see Fingerprint64) as 8 bytes in little-endian byte order
+	FingerprintSchema
+	// RegistryIDSchema messages include the ID of the schema in a
+	// SchemaRegistry as an unsigned variable-size integer
+	RegistryIDSchema
+)
+
+func (ref SchemaReference) String() string {
+	switch ref {
+	case InlineSchema:
+		return "inline"
+	case FingerprintSchema:
+		return "fingerprint"
+	case RegistryIDSchema:
+		return "registry ID"
+	}
+	return fmt.Sprintf("SchemaReference(%d)", byte(ref))
+}
+
+// WriteMessage writes a message containing the schema s and the value v
+// encoded using s to w
+func WriteMessage(w io.Writer, s Schema, v interface{}) error {
+	return WriteMessageRef(w, InlineSchema, nil, s, v)
+}
+
+// WriteMessageRef writes a message containing a reference to the schema s and
+// the value v encoded using s to w. For RegistryIDSchema references, s is
+// registered with reg (which is required) to obtain its ID. For
+// FingerprintSchema references, s is registered with reg if reg is not nil,
+// so that readers using the same registry can find it.
+func WriteMessageRef(w io.Writer, ref SchemaReference, reg SchemaRegistry, s Schema, v interface{}) error {
+	if s == nil {
+		return fmt.Errorf("cannot write message with nil schema")
+	}
+
+	header := []byte{MessageMagic, MessageVersion, byte(ref)}
+	switch ref {
+	case InlineSchema:
+		m, ok := s.(Marshaler)
+		if !ok {
+			return fmt.Errorf("schema does not implement MarshalSchemer")
+		}
+		b, err := m.MarshalSchemer()
+		if err != nil {
+			return err
+		}
+		header = append(header, b...)
+
+	case FingerprintSchema:
+		if reg != nil {
+			if _, err := reg.Register(s); err != nil {
+				return err
+			}
+		}
+		fp, err := Fingerprint64(s)
+		if err != nil {
+			return err
+		}
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], fp)
+		header = append(header, buf[:]...)
+
+	case RegistryIDSchema:
+		if reg == nil {
+			return fmt.Errorf("schema registry is required for %v references", ref)
+		}
+		id, err := reg.Register(s)
+		if err != nil {
+			return err
+		}
+		header = appendUvarint(header, id)
+
+	default:
+		return fmt.Errorf("unknown schema reference %v", ref)
+	}
+
+	// The value is encoded before anything is written, so that nothing is
+	// written if it cannot be encoded
+	b, err := appendEncodeValue(s, header, reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// Message is a message read by ReadMessage. The value of the message has not
+// yet been read; it can be decoded using Decode or DecodeValue, or read
+// directly from Reader.
+type Message struct {
+	// Schema is the schema used to encode the value (i.e. the writer schema)
+	Schema Schema
+
+	// Reference indicates how the schema was identified by the message
+	Reference SchemaReference
+
+	// ID is the registry ID of the schema for RegistryIDSchema references
+	ID uint64
+
+	// Fingerprint is the 64-bit fingerprint of the schema for
+	// FingerprintSchema references
+	Fingerprint uint64
+
+	r io.Reader
+}
+
+// ReadMessage reads the header of a message from r and returns the Message.
+// reg is used to look up schemas that are not included in the message; it may
+// be nil if all messages include their schemas.
+func ReadMessage(r io.Reader, reg SchemaRegistry) (*Message, error) {
+	header := make([]byte, 3)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, err
+	}
+	if header[0] != MessageMagic {
+		return nil, fmt.Errorf("invalid message magic byte 0x%02x", header[0])
+	}
+	if header[1] != MessageVersion {
+		return nil, fmt.Errorf("unsupported message version %d", header[1])
+	}
+
+	m := &Message{Reference: SchemaReference(header[2]), r: r}
+	if m.Reference != InlineSchema && reg == nil {
+		return nil, fmt.Errorf("schema registry is required for %v references", m.Reference)
+	}
+
+	switch m.Reference {
+	case InlineSchema:
+		m.Schema, err = DecodeSchema(r)
+
+	case FingerprintSchema:
+		var buf [8]byte
+		_, err = io.ReadFull(r, buf[:])
+		if err != nil {
+			return nil, err
+		}
+		m.Fingerprint = binary.LittleEndian.Uint64(buf[:])
+		m.ID, m.Schema, err = reg.LookupFingerprint(m.Fingerprint)
+
+	case RegistryIDSchema:
+		m.ID, err = ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		m.Schema, err = reg.Lookup(m.ID)
+
+	default:
+		return nil, fmt.Errorf("unknown schema reference %v", m.Reference)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reader returns the reader positioned at the encoded value of the message
+func (m *Message) Reader() io.Reader {
+	return m.r
+}
+
+// Decode reads the value of the message and stores it in i
+func (m *Message) Decode(i interface{}) error {
+	return m.Schema.Decode(m.r, i)
+}
+
+// DecodeValue reads the value of the message and stores it in v
+func (m *Message) DecodeValue(v reflect.Value) error {
+	return m.Schema.DecodeValue(m.r, v)
+}
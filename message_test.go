@@ -0,0 +1,93 @@
+package schemer
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMessage(t *testing.T) {
+
+	value := testRecord{ID: 7, Name: "seven", Tags: []string{"a"}}
+	s, err := SchemaOf(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := NewMemorySchemaRegistry()
+
+	for _, ref := range []SchemaReference{InlineSchema, FingerprintSchema, RegistryIDSchema} {
+		var buf bytes.Buffer
+		err = WriteMessageRef(&buf, ref, reg, s, value)
+		if err != nil {
+			t.Fatalf("%v: %v", ref, err)
+		}
+		// a second message follows the first
+		err = WriteMessage(&buf, &VarStringSchema{}, "next")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := ReadMessage(&buf, reg)
+		if err != nil {
+			t.Fatalf("%v: %v", ref, err)
+		}
+		if m.Reference != ref || m.Schema.GoType() != s.GoType() {
+			t.Errorf("%v: unexpected message %+v", ref, m)
+		}
+		if ref != InlineSchema && m.ID != 1 {
+			t.Errorf("%v: unexpected ID %d", ref, m.ID)
+		}
+
+		var decoded testRecord
+		err = m.Decode(&decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, value) {
+			t.Errorf("%v: expected %v; got %v", ref, value, decoded)
+		}
+
+		m, err = ReadMessage(&buf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var str string
+		if err = m.Decode(&str); err != nil || str != "next" {
+			t.Errorf("unexpected second message %q, %v", str, err)
+		}
+	}
+}
+
+func TestMessageErrors(t *testing.T) {
+
+	var buf bytes.Buffer
+	s := &BoolSchema{}
+	if err := WriteMessageRef(&buf, RegistryIDSchema, nil, s, true); err == nil {
+		t.Error("expected error writing registry ID without a registry")
+	}
+
+	// nothing is written if the value cannot be encoded
+	if err := WriteMessage(&buf, s, "not a bool"); err == nil || buf.Len() > 0 {
+		t.Errorf("expected error and no output; got %v and %d bytes", err, buf.Len())
+	}
+
+	if err := WriteMessageRef(&buf, FingerprintSchema, nil, s, true); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if _, err := ReadMessage(bytes.NewReader(data), nil); err == nil {
+		t.Error("expected error reading fingerprint without a registry")
+	}
+	if _, err := ReadMessage(bytes.NewReader(data), NewMemorySchemaRegistry()); err == nil {
+		t.Error("expected error reading unregistered fingerprint")
+	}
+
+	data[0] = 0
+	if _, err := ReadMessage(bytes.NewReader(data), nil); err == nil {
+		t.Error("expected error reading invalid magic byte")
+	}
+	data[0], data[1] = MessageMagic, MessageVersion+1
+	if _, err := ReadMessage(bytes.NewReader(data), nil); err == nil {
+		t.Error("expected error reading unsupported version")
+	}
+}
@@ -0,0 +1,962 @@
+package schemer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Plan is an encoder and decoder for a specific Schema and Go type. The
+// decisions that a Schema makes each time a value is encoded or decoded (i.e.
+// checking the kind of the value, matching struct fields to object fields,
+// etc.) are made once when the Plan is compiled.
+// Values are encoded exactly as they would be by the Schema. Plans fall back to
+// calling EncodeValue and DecodeValue on the Schema for Go types and schemas
+// that they cannot handle directly (i.e. interfaces and schemas of custom
+// types). A Plan is safe for concurrent use by multiple goroutines.
+type Plan struct {
+	schema Schema
+	t      reflect.Type
+	enc    encoderFunc
+	dec    decoderFunc
+}
+
+// encoderFunc appends the encoded value of v to b
+type encoderFunc func(b []byte, v reflect.Value) ([]byte, error)
+
+// decoderFunc reads the next encoded value from r and stores it in v, which is
+// always settable
+type decoderFunc func(r decodeReader, v reflect.Value) error
+
+// decodeReader is the input stream used by decoderFunc
+type decodeReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// encodeBufPool holds buffers used by EncodeValue methods that encode values
+// using AppendEncodeValue
+var encodeBufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 512)
+		return &b
+	},
+}
+
+// maxPooledBufSize is the capacity above which buffers are not returned to
+// encodeBufPool
+const maxPooledBufSize = 64 * 1024
+
+var timeType = reflect.TypeOf(time.Time{})
+
+var errNotNullable = errors.New("cannot encode nil value: schema is not nullable")
+
+// Compile returns a Plan for encoding values of Go type t using schema s and
+// for decoding values encoded with schema s into values of Go type t.
+func Compile(s Schema, t reflect.Type) (*Plan, error) {
+	if s == nil {
+		return nil, fmt.Errorf("cannot compile nil schema")
+	}
+	if t == nil {
+		return nil, fmt.Errorf("cannot compile plan for nil type")
+	}
+	enc, err := compileEncoder(s, t)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := compileDecoder(s, t)
+	if err != nil {
+		return nil, err
+	}
+	return &Plan{schema: s, t: t, enc: enc, dec: dec}, nil
+}
+
+// Schema returns the Schema that p was compiled with
+func (p *Plan) Schema() Schema {
+	return p.schema
+}
+
+// GoType returns the Go type that p was compiled with
+func (p *Plan) GoType() reflect.Type {
+	return p.t
+}
+
+// Encode writes the encoded value of i to the output stream. i must be of the
+// plan's Go type or a pointer to it.
+func (p *Plan) Encode(w io.Writer, i interface{}) error {
+	return p.EncodeValue(w, reflect.ValueOf(i))
+}
+
+// EncodeValue writes the encoded value of v to the output stream. v must be of
+// the plan's Go type or a pointer to it.
+func (p *Plan) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, p, v)
+}
+
+// AppendEncode appends the encoded value of i to dst and returns the extended
+// slice. i must be of the plan's Go type or a pointer to it.
+func (p *Plan) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return p.appendValue(dst, reflect.ValueOf(i))
+}
+
+// AppendEncodeValue appends the encoded value of v to dst and returns the
+// extended slice. v must be of the plan's Go type or a pointer to it.
+func (p *Plan) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
+	return p.appendValue(dst, v)
+}
+
+// appendValue appends the encoded value of v to b
+func (p *Plan) appendValue(b []byte, v reflect.Value) ([]byte, error) {
+	if v.IsValid() && v.Type() != p.t {
+		if v.Kind() != reflect.Ptr || v.Type().Elem() != p.t {
+			return b, fmt.Errorf("plan for %v cannot encode %v", p.t, v.Type())
+		}
+		if v.IsNil() {
+			return b, fmt.Errorf("cannot encode nil %v", v.Type())
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		// Let the Schema decide if nil is acceptable
+		return fallbackEncoder(p.schema)(b, v)
+	}
+	return p.enc(b, v)
+}
+
+// Decode reads the next encoded value from the input stream and stores it in
+// i, which must be a non-nil pointer to a value of the plan's Go type.
+func (p *Plan) Decode(r io.Reader, i interface{}) error {
+	if i == nil {
+		return fmt.Errorf("cannot decode to nil destination")
+	}
+	return p.DecodeValue(r, reflect.ValueOf(i))
+}
+
+// DecodeValue reads the next encoded value from the input stream and stores
+// it in v, which must be a settable value of the plan's Go type or a non-nil
+// pointer to it.
+func (p *Plan) DecodeValue(r io.Reader, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr && v.Type().Elem() == p.t && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Type() != p.t {
+		return fmt.Errorf("plan for %v cannot decode to %v", p.t, v.Type())
+	}
+	if !v.CanSet() {
+		return fmt.Errorf("decode destination is not settable")
+	}
+	return p.dec(newDecodeReader(r), v)
+}
+
+// DecodeBytes decodes the encoded value at the start of data, stores it in i,
+// which must be a non-nil pointer to a value of the plan's Go type, and
+// returns the number of bytes read
+func (p *Plan) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(p, data, i)
+}
+
+// newDecodeReader returns r as a decodeReader
+func newDecodeReader(r io.Reader) decodeReader {
+	if dr, ok := r.(decodeReader); ok {
+		return dr
+	}
+	return byter{r}
+}
+
+// nullable returns true if s is nullable
+func nullable(s Schema) bool {
+	opt, ok := s.(interface{ Nullable() bool })
+	return ok && opt.Nullable()
+}
+
+// derefType removes all levels of pointer indirection from t and returns the
+// resulting type and the number of pointers removed
+func derefType(t reflect.Type) (reflect.Type, int) {
+	ptrs := 0
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		ptrs++
+	}
+	return t, ptrs
+}
+
+// appendWriter is an io.Writer that appends to a byte slice
+type appendWriter struct {
+	b []byte
+}
+
+func (w *appendWriter) Write(p []byte) (int, error) {
+	w.b = append(w.b, p...)
+	return len(p), nil
+}
+
+// fallbackEncoder returns an encoderFunc that calls s.AppendEncodeValue or
+// s.EncodeValue
+func fallbackEncoder(s Schema) encoderFunc {
+	if a, ok := s.(Appender); ok {
+		return a.AppendEncodeValue
+	}
+	return func(b []byte, v reflect.Value) ([]byte, error) {
+		w := appendWriter{b}
+		err := s.EncodeValue(&w, v)
+		return w.b, err
+	}
+}
+
+// fallbackDecoder returns a decoderFunc that calls s.DecodeValue
+func fallbackDecoder(s Schema) decoderFunc {
+	return func(r decodeReader, v reflect.Value) error {
+		return s.DecodeValue(r, v)
+	}
+}
+
+// compileEncoder returns an encoderFunc for values of type t, handling the
+// null byte and pointer indirection before calling the encoder of the
+// underlying type
+func compileEncoder(s Schema, t reflect.Type) (encoderFunc, error) {
+	base, ptrs := derefType(t)
+	if base.Kind() == reflect.Interface {
+		return fallbackEncoder(s), nil
+	}
+	enc, err := compileBaseEncoder(s, base)
+	if err != nil || enc == nil {
+		return fallbackEncoder(s), err
+	}
+
+	isNullable := nullable(s)
+	if ptrs == 0 && !isNullable {
+		return enc, nil
+	}
+	return func(b []byte, v reflect.Value) ([]byte, error) {
+		for i := 0; i < ptrs; i++ {
+			if v.IsNil() {
+				if isNullable {
+					// 1 indicates null
+					return append(b, 1), nil
+				}
+				return b, errNotNullable
+			}
+			v = v.Elem()
+		}
+		if isNullable {
+			// 0 indicates not null
+			b = append(b, 0)
+		}
+		return enc(b, v)
+	}, nil
+}
+
+// compileBaseEncoder returns an encoderFunc for values of type t, which is not
+// a pointer type, or nil if the plan should fall back to s.EncodeValue
+func compileBaseEncoder(s Schema, t reflect.Type) (encoderFunc, error) {
+	k := t.Kind()
+
+	switch s := s.(type) {
+	case *VarIntSchema:
+		switch {
+		case isIntKind(k) && s.Signed:
+			return func(b []byte, v reflect.Value) ([]byte, error) {
+				intVal := v.Int()
+				uintVal := uint64(intVal) << 1
+				if intVal < 0 {
+					uintVal = ^uintVal
+				}
+				return appendUvarint(b, uintVal), nil
+			}, nil
+		case isIntKind(k):
+			return func(b []byte, v reflect.Value) ([]byte, error) {
+				intVal := v.Int()
+				if intVal < 0 {
+					return b, fmt.Errorf("cannot encode negative integer")
+				}
+				return appendUvarint(b, uint64(intVal)), nil
+			}, nil
+		case isUintKind(k) && s.Signed:
+			return func(b []byte, v reflect.Value) ([]byte, error) {
+				return appendUvarint(b, v.Uint()<<1), nil
+			}, nil
+		case isUintKind(k):
+			return func(b []byte, v reflect.Value) ([]byte, error) {
+				return appendUvarint(b, v.Uint()), nil
+			}, nil
+		}
+
+	case *DateSchema:
+		if t == timeType {
+			return func(b []byte, v reflect.Value) ([]byte, error) {
+				intVal := v.Interface().(time.Time).UnixNano() / 1000000
+				uintVal := uint64(intVal) << 1
+				if intVal < 0 {
+					uintVal = ^uintVal
+				}
+				return appendUvarint(b, uintVal), nil
+			}, nil
+		}
+
+	case *BoolSchema:
+		if k == reflect.Bool {
+			return func(b []byte, v reflect.Value) ([]byte, error) {
+				if v.Bool() {
+					return append(b, 1), nil
+				}
+				return append(b, 0), nil
+			}, nil
+		}
+
+	case *FloatSchema:
+		if s.Bits == 32 && k == reflect.Float32 {
+			return func(b []byte, v reflect.Value) ([]byte, error) {
+				i := math.Float32bits(float32(v.Float()))
+				return append(b, byte(i), byte(i>>8), byte(i>>16), byte(i>>24)), nil
+			}, nil
+		}
+		if s.Bits == 64 && (k == reflect.Float32 || k == reflect.Float64) {
+			return func(b []byte, v reflect.Value) ([]byte, error) {
+				i := math.Float64bits(v.Float())
+				return append(b,
+					byte(i), byte(i>>8), byte(i>>16), byte(i>>24),
+					byte(i>>32), byte(i>>40), byte(i>>48), byte(i>>56),
+				), nil
+			}, nil
+		}
+
+	case *VarStringSchema:
+		if k == reflect.String {
+			return func(b []byte, v reflect.Value) ([]byte, error) {
+				str := v.String()
+				b = appendUvarint(b, uint64(len(str)))
+				return append(b, str...), nil
+			}, nil
+		}
+
+	case *VarBytesSchema:
+		if k == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return func(b []byte, v reflect.Value) ([]byte, error) {
+				bytes := v.Bytes()
+				b = appendUvarint(b, uint64(len(bytes)))
+				return append(b, bytes...), nil
+			}, nil
+		}
+
+	case *FixedBytesSchema:
+		if k == reflect.Array && t.Len() == s.Length && t.Elem().Kind() == reflect.Uint8 {
+			return func(b []byte, v reflect.Value) ([]byte, error) {
+				return appendBytesValue(b, v), nil
+			}, nil
+		}
+
+	case *FixedArraySchema:
+		if k != reflect.Array || t.Len() != s.Length || s.Element == nil {
+			break
+		}
+		if l, ok := bulkLayoutOf(s.Element, t.Elem()); ok {
+			return func(b []byte, v reflect.Value) ([]byte, error) {
+				return appendBulk(b, v, l), nil
+			}, nil
+		}
+		elemEnc, err := compileEncoder(s.Element, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(b []byte, v reflect.Value) ([]byte, error) {
+			var err error
+			for i := 0; i < v.Len(); i++ {
+				b, err = elemEnc(b, v.Index(i))
+				if err != nil {
+					return b, err
+				}
+			}
+			return b, nil
+		}, nil
+
+	case *VarArraySchema:
+		if k != reflect.Slice || s.Element == nil {
+			break
+		}
+		if l, ok := bulkLayoutOf(s.Element, t.Elem()); ok {
+			return func(b []byte, v reflect.Value) ([]byte, error) {
+				b = appendUvarint(b, uint64(v.Len()))
+				return appendBulk(b, v, l), nil
+			}, nil
+		}
+		elemEnc, err := compileEncoder(s.Element, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(b []byte, v reflect.Value) ([]byte, error) {
+			n := v.Len()
+			b = appendUvarint(b, uint64(n))
+			var err error
+			for i := 0; i < n; i++ {
+				b, err = elemEnc(b, v.Index(i))
+				if err != nil {
+					return b, err
+				}
+			}
+			return b, nil
+		}, nil
+
+	case *VarObjectSchema:
+		if k != reflect.Map || s.Key == nil || s.Value == nil {
+			break
+		}
+		keyEnc, err := compileEncoder(s.Key, t.Key())
+		if err != nil {
+			return nil, err
+		}
+		valEnc, err := compileEncoder(s.Value, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(b []byte, v reflect.Value) ([]byte, error) {
+			b = appendUvarint(b, uint64(v.Len()))
+			var err error
+			iter := v.MapRange()
+			for iter.Next() {
+				b, err = keyEnc(b, iter.Key())
+				if err != nil {
+					return b, err
+				}
+				b, err = valEnc(b, iter.Value())
+				if err != nil {
+					return b, err
+				}
+			}
+			return b, nil
+		}, nil
+
+	case *FixedObjectSchema:
+		// Note: object fields are encoded from struct fields by index
+		if k != reflect.Struct || t.NumField() < len(s.Fields) {
+			break
+		}
+		fieldEncs := make([]encoderFunc, len(s.Fields))
+		for i, f := range s.Fields {
+			if f.Schema == nil {
+				return nil, fmt.Errorf("object field %d has no schema", i)
+			}
+			enc, err := compileEncoder(f.Schema, t.Field(i).Type)
+			if err != nil {
+				return nil, fmt.Errorf("struct field %v: %w", t.Field(i).Name, err)
+			}
+			fieldEncs[i] = enc
+		}
+		return func(b []byte, v reflect.Value) ([]byte, error) {
+			var err error
+			for i, enc := range fieldEncs {
+				b, err = enc(b, v.Field(i))
+				if err != nil {
+					return b, err
+				}
+			}
+			return b, nil
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// compileDecoder returns a decoderFunc for values of type t, handling the
+// null byte and pointer indirection before calling the decoder of the
+// underlying type
+func compileDecoder(s Schema, t reflect.Type) (decoderFunc, error) {
+	base, ptrs := derefType(t)
+	if base.Kind() == reflect.Interface {
+		return fallbackDecoder(s), nil
+	}
+	dec, err := compileBaseDecoder(s, base)
+	if err != nil || dec == nil {
+		return fallbackDecoder(s), err
+	}
+
+	isNullable := nullable(s)
+	if ptrs == 0 && !isNullable {
+		return dec, nil
+	}
+	return func(r decodeReader, v reflect.Value) error {
+		if isNullable {
+			c, err := r.ReadByte()
+			if err != nil {
+				return err
+			}
+			if c == 1 {
+				if ptrs == 0 {
+					return fmt.Errorf("cannot decode null value to a %s", v.Kind())
+				}
+				v.Set(reflect.Zero(v.Type()))
+				return nil
+			}
+		}
+		for i := 0; i < ptrs; i++ {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		return dec(r, v)
+	}, nil
+}
+
+// compileBaseDecoder returns a decoderFunc for values of type t, which is not
+// a pointer type, or nil if the plan should fall back to s.DecodeValue
+func compileBaseDecoder(s Schema, t reflect.Type) (decoderFunc, error) {
+	k := t.Kind()
+
+	switch s := s.(type) {
+	case *VarIntSchema:
+		if !isIntKind(k) && !isUintKind(k) {
+			break
+		}
+		signed := s.Signed
+		return func(r decodeReader, v reflect.Value) error {
+			uintVal, err := binary.ReadUvarint(r)
+			if err != nil {
+				return err
+			}
+			if signed {
+				intVal := int64(uintVal >> 1)
+				if uintVal&1 != 0 {
+					intVal = ^intVal
+				}
+				return setInt(v, intVal)
+			}
+			return setUint(v, uintVal)
+		}, nil
+
+	case *DateSchema:
+		if t == timeType {
+			return func(r decodeReader, v reflect.Value) error {
+				uintVal, err := binary.ReadUvarint(r)
+				if err != nil {
+					return err
+				}
+				intVal := int64(uintVal >> 1)
+				if uintVal&1 != 0 {
+					intVal = ^intVal
+				}
+				v.Set(reflect.ValueOf(time.Unix(0, intVal*1000000)))
+				return nil
+			}, nil
+		}
+
+	case *BoolSchema:
+		if k == reflect.Bool {
+			return func(r decodeReader, v reflect.Value) error {
+				c, err := r.ReadByte()
+				if err != nil {
+					return err
+				}
+				v.SetBool(c > 0)
+				return nil
+			}, nil
+		}
+
+	case *FloatSchema:
+		if (s.Bits != 32 && s.Bits != 64) || (k != reflect.Float32 && k != reflect.Float64) {
+			break
+		}
+		bits := s.Bits
+		return func(r decodeReader, v reflect.Value) error {
+			var buf [8]byte
+			var f float64
+			if bits == 32 {
+				_, err := io.ReadFull(r, buf[:4])
+				if err != nil {
+					return err
+				}
+				f = float64(math.Float32frombits(uint32(buf[0]) |
+					uint32(buf[1])<<8 |
+					uint32(buf[2])<<16 |
+					uint32(buf[3])<<24))
+			} else {
+				_, err := io.ReadFull(r, buf[:])
+				if err != nil {
+					return err
+				}
+				f = math.Float64frombits(uint64(buf[0]) |
+					uint64(buf[1])<<8 |
+					uint64(buf[2])<<16 |
+					uint64(buf[3])<<24 |
+					uint64(buf[4])<<32 |
+					uint64(buf[5])<<40 |
+					uint64(buf[6])<<48 |
+					uint64(buf[7])<<56)
+			}
+			if v.Kind() == reflect.Float32 && v.OverflowFloat(f) {
+				return fmt.Errorf("decoded value %f overflows destination %v", f, v.Kind())
+			}
+			v.SetFloat(f)
+			return nil
+		}, nil
+
+	case *VarStringSchema:
+		if k == reflect.String {
+			return func(r decodeReader, v reflect.Value) error {
+				n, err := binary.ReadUvarint(r)
+				if err != nil {
+					return err
+				}
+				str, err := readString(r, n)
+				if err != nil {
+					return err
+				}
+				v.SetString(str)
+				return nil
+			}, nil
+		}
+
+	case *VarBytesSchema:
+		if k == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return func(r decodeReader, v reflect.Value) error {
+				n, err := binary.ReadUvarint(r)
+				if err != nil {
+					return err
+				}
+				return decodeBytesValue(r, n, v, false)
+			}, nil
+		}
+
+	case *FixedBytesSchema:
+		if k == reflect.Array && t.Len() == s.Length && t.Elem().Kind() == reflect.Uint8 {
+			return func(r decodeReader, v reflect.Value) error {
+				return decodeBytesValue(r, uint64(s.Length), v, false)
+			}, nil
+		}
+
+	case *FixedArraySchema:
+		if k != reflect.Array || t.Len() != s.Length || s.Element == nil {
+			break
+		}
+		if l, ok := bulkLayoutOf(s.Element, t.Elem()); ok {
+			return func(r decodeReader, v reflect.Value) error {
+				return readBulk(r, v, l)
+			}, nil
+		}
+		elemDec, err := compileDecoder(s.Element, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(r decodeReader, v reflect.Value) error {
+			for i := 0; i < v.Len(); i++ {
+				err := elemDec(r, v.Index(i))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+
+	case *VarArraySchema:
+		if k != reflect.Slice || s.Element == nil {
+			break
+		}
+		if l, ok := bulkLayoutOf(s.Element, t.Elem()); ok {
+			return func(r decodeReader, v reflect.Value) error {
+				n, err := binary.ReadUvarint(r)
+				if err != nil {
+					return err
+				}
+				if v.IsNil() || uint64(v.Len()) != n {
+					slice, err := readBulkSlice(r, t, n, l)
+					if err != nil {
+						return err
+					}
+					v.Set(slice)
+					return nil
+				}
+				return readBulk(r, v, l)
+			}, nil
+		}
+		elemDec, err := compileDecoder(s.Element, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		elemSize := minEncodedSize(s.Element)
+		return func(r decodeReader, v reflect.Value) error {
+			n, err := binary.ReadUvarint(r)
+			if err != nil {
+				return err
+			}
+			// Reuse the existing slice only if it has the expected length
+			if v.IsNil() || uint64(v.Len()) != n {
+				slice, err := decodeSlice(r, t, n, elemSize, func(v reflect.Value) error {
+					return elemDec(r, v)
+				})
+				if err != nil {
+					return err
+				}
+				v.Set(slice)
+				return nil
+			}
+			for i := 0; i < v.Len(); i++ {
+				err := elemDec(r, v.Index(i))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+
+	case *VarObjectSchema:
+		if k != reflect.Map || s.Key == nil || s.Value == nil {
+			break
+		}
+		keyDec, err := compileDecoder(s.Key, t.Key())
+		if err != nil {
+			return nil, err
+		}
+		valDec, err := compileDecoder(s.Value, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		entrySize := minEncodedSize(s.Key) + minEncodedSize(s.Value)
+		return func(r decodeReader, v reflect.Value) error {
+			n, err := binary.ReadUvarint(r)
+			if err != nil {
+				return err
+			}
+			if _, err := checkLen(r, n, entrySize); err != nil {
+				return err
+			}
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(t))
+			}
+			for i := uint64(0); i < n; i++ {
+				key := reflect.New(t.Key()).Elem()
+				val := reflect.New(t.Elem()).Elem()
+				if err := keyDec(r, key); err != nil {
+					return err
+				}
+				if err := valDec(r, val); err != nil {
+					return err
+				}
+				v.SetMapIndex(key, val)
+			}
+			return nil
+		}, nil
+
+	case *FixedObjectSchema:
+		if k != reflect.Struct {
+			break
+		}
+		fieldDecs := make([]decoderFunc, len(s.Fields))
+		populated := make(map[int]bool, len(s.Fields))
+		for i, f := range s.Fields {
+			if f.Schema == nil {
+				return nil, fmt.Errorf("object field %d has no schema", i)
+			}
+			dec, err := compileFieldDecoder(f, t)
+			if err != nil {
+				return nil, err
+			}
+			fieldDecs[i] = dec
+			for _, index := range findStructFields(f.Aliases, t) {
+				populated[index] = true
+			}
+		}
+
+		// fields that are not populated and have default values in their
+		// struct tags
+		fields := structFieldsOf(t)
+		var defaults []int
+		for i := 0; i < t.NumField(); i++ {
+			if populated[i] || t.Field(i).PkgPath != "" {
+				continue
+			}
+			if fields[i].tag.DefaultSet {
+				defaults = append(defaults, i)
+			}
+		}
+
+		return func(r decodeReader, v reflect.Value) error {
+			for _, dec := range fieldDecs {
+				if err := dec(r, v); err != nil {
+					return err
+				}
+			}
+			for _, i := range defaults {
+				if err := fields[i].setDefault(v.Field(i)); err != nil {
+					return fmt.Errorf("struct field %v: %w", t.Field(i).Name, err)
+				}
+			}
+			return nil
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// compileFieldDecoder returns a decoderFunc that decodes object field f into
+// the fields of a struct of type t that match its aliases. If there is no
+// matching field, the decoded value is discarded.
+func compileFieldDecoder(f ObjectField, t reflect.Type) (decoderFunc, error) {
+	indexes := findStructFields(f.Aliases, t)
+	if len(indexes) == 0 {
+		s := f.Schema
+		return func(r decodeReader, v reflect.Value) error {
+			// there is no where to put the field, but we still need to
+			// process the bytes of the encoded data
+			var ignoreMe interface{}
+			return s.Decode(r, &ignoreMe)
+		}, nil
+	}
+
+	decs := make([]decoderFunc, len(indexes))
+	for i, index := range indexes {
+		dec, err := compileStructFieldDecoder(f.Schema, t, index)
+		if err != nil {
+			return nil, err
+		}
+		decs[i] = dec
+	}
+	if len(decs) == 1 {
+		return decs[0], nil
+	}
+	return func(r decodeReader, v reflect.Value) error {
+		return decodeRepeated(r, len(decs), func(r decodeReader, i int) error {
+			return decs[i](r, v)
+		})
+	}, nil
+}
+
+// compileStructFieldDecoder returns a decoderFunc that decodes values of
+// schema s into the field of a struct of type t with the specified index
+func compileStructFieldDecoder(s Schema, t reflect.Type, index int) (decoderFunc, error) {
+	sf := t.Field(index)
+	if sf.PkgPath != "" {
+		// unexported fields are not settable; let the Schema report the error
+		return func(r decodeReader, v reflect.Value) error {
+			return s.DecodeValue(r, v.Field(index))
+		}, nil
+	}
+
+	dec, err := compileDecoder(s, sf.Type)
+	if err != nil {
+		return nil, fmt.Errorf("struct field %v: %w", sf.Name, err)
+	}
+	return func(r decodeReader, v reflect.Value) error {
+		return dec(r, v.Field(index))
+	}, nil
+}
+
+// findStructFields returns the indexes of the fields of struct type t whose
+// name or struct tag aliases match the specified aliases. Each alias matches
+// the first such field (see findStructField); each index is returned once.
+func findStructFields(aliases []string, t reflect.Type) []int {
+	fields := structFieldsOf(t)
+	var indexes []int
+	for _, alias := range aliases {
+		index := findStructField(alias, t, fields)
+		if index >= 0 && !containsInt(indexes, index) {
+			indexes = append(indexes, index)
+		}
+	}
+	return indexes
+}
+
+// findStructField returns the index of the first field of struct type t whose
+// name or struct tag aliases match alias, or -1 if no field matches. fields
+// holds the parsed struct tags of t (see structFieldsOf).
+func findStructField(alias string, t reflect.Type, fields []structField) int {
+	for i := 0; i < t.NumField(); i++ {
+		if alias == t.Field(i).Name {
+			return i
+		}
+		for _, fieldAlias := range fields[i].tag.FieldAliases {
+			if alias == fieldAlias {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// containsInt returns true if list contains x
+func containsInt(list []int, x int) bool {
+	for _, y := range list {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeRepeated calls dec n times to decode the same encoded value into n
+// destinations. The first call reads the value from r; the others read a copy
+// of the bytes read by the first call.
+func decodeRepeated(r decodeReader, n int, dec func(r decodeReader, i int) error) error {
+	rec := recordingReader{r: r}
+	if err := dec(&rec, 0); err != nil {
+		return err
+	}
+	var copied bytes.Reader
+	for i := 1; i < n; i++ {
+		copied.Reset(rec.b)
+		if err := dec(&copied, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setInt stores intVal in v, which must be an integer type
+func setInt(v reflect.Value, intVal int64) error {
+	k := v.Kind()
+	if isIntKind(k) {
+		if v.OverflowInt(intVal) {
+			return fmt.Errorf("decoded value %d overflows destination %v", intVal, k)
+		}
+		v.SetInt(intVal)
+		return nil
+	}
+	if intVal < 0 {
+		return fmt.Errorf("decoded value %d incompatible with %v", intVal, k)
+	}
+	uintVal := uint64(intVal)
+	if v.OverflowUint(uintVal) {
+		return fmt.Errorf("decoded value %d overflows destination %v", uintVal, k)
+	}
+	v.SetUint(uintVal)
+	return nil
+}
+
+// setUint stores uintVal in v, which must be an integer type
+func setUint(v reflect.Value, uintVal uint64) error {
+	k := v.Kind()
+	if isIntKind(k) {
+		intVal := int64(uintVal)
+		if intVal < 0 || v.OverflowInt(intVal) {
+			return fmt.Errorf("decoded value %d overflows destination %v", uintVal, k)
+		}
+		v.SetInt(intVal)
+		return nil
+	}
+	if v.OverflowUint(uintVal) {
+		return fmt.Errorf("decoded value %d overflows destination %v", uintVal, k)
+	}
+	v.SetUint(uintVal)
+	return nil
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uint64
+}
+
+// appendUvarint appends the Uvarint encoding of x to b
+func appendUvarint(b []byte, x uint64) []byte {
+	for x >= 0x80 {
+		b = append(b, byte(x)|0x80)
+		x >>= 7
+	}
+	return append(b, byte(x))
+}
@@ -0,0 +1,361 @@
+package schemer
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type planStruct struct {
+	ID      int64
+	Name    string
+	Score   float64
+	Ratio   float32
+	Active  bool
+	Count   uint16
+	Tags    []string
+	Flag    *bool
+	Scores  [3]float32
+	Nested  *embeddedStruct
+	Extra   map[string]int
+	Created time.Time
+	Any     interface{}
+}
+
+func newPlanStruct(i int) planStruct {
+	flag := i%2 == 0
+	s := planStruct{
+		ID:      int64(i) * -1000,
+		Name:    "record",
+		Score:   float64(i) / 3,
+		Ratio:   0.5,
+		Active:  flag,
+		Count:   uint16(i),
+		Tags:    []string{"a", "bb", "ccc"},
+		Scores:  [3]float32{1, 2, 3},
+		Extra:   map[string]int{"x": i},
+		Created: time.Unix(int64(i), 0),
+		Any:     "variant",
+	}
+	if flag {
+		s.Flag = &flag
+		s.Nested = &embeddedStruct{Int1: int64(i)}
+	}
+	return s
+}
+
+func TestPlanEncode(t *testing.T) {
+
+	s, err := SchemaOf(planStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Compile(s, reflect.TypeOf(planStruct{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		value := newPlanStruct(i)
+
+		var expected, actual bytes.Buffer
+		if err = s.Encode(&expected, value); err != nil {
+			t.Fatal(err)
+		}
+		if err = p.Encode(&actual, value); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(expected.Bytes(), actual.Bytes()) {
+			t.Fatalf("%d: plan encoding differs from schema encoding", i)
+		}
+
+		// pointers to the plan's type are accepted as well
+		actual.Reset()
+		if err = p.Encode(&actual, &value); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(expected.Bytes(), actual.Bytes()) {
+			t.Fatalf("%d: plan encoding of pointer differs", i)
+		}
+
+		var decoded planStruct
+		if err = p.Decode(bytes.NewReader(expected.Bytes()), &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Created.Equal(value.Created) {
+			t.Errorf("%d: unexpected time decode", i)
+		}
+		decoded.Created = value.Created
+		if !reflect.DeepEqual(decoded, value) {
+			t.Errorf("%d: expected %#v; got %#v", i, value, decoded)
+		}
+	}
+
+	if err = p.Encode(&bytes.Buffer{}, 42); err == nil {
+		t.Error("expected error encoding int with struct plan")
+	}
+	var i int
+	if err = p.Decode(bytes.NewReader(nil), &i); err == nil {
+		t.Error("expected error decoding to int with struct plan")
+	}
+}
+
+// make sure plans match struct fields by name and alias when decoding
+func TestPlanDecodeFields(t *testing.T) {
+
+	type source struct {
+		A int
+		B string
+		C []float64
+	}
+	type dest struct {
+		C2 []float64 `schemer:"[C]"`
+		A  int8
+		D  bool
+	}
+
+	s, err := SchemaOf(source{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = s.Encode(&buf, source{A: 12, B: "skipped", C: []float64{1.5, 2.5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := Compile(s, reflect.TypeOf(dest{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded dest
+	err = p.Decode(bytes.NewReader(buf.Bytes()), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := dest{C2: []float64{1.5, 2.5}, A: 12}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("expected %#v; got %#v", expected, decoded)
+	}
+
+	// overflows are reported
+	buf.Reset()
+	err = s.Encode(&buf, source{A: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Decode(bytes.NewReader(buf.Bytes()), &decoded)
+	if err == nil {
+		t.Error("expected overflow error")
+	}
+}
+
+// plans and FixedObjectSchema.DecodeValue populate every struct field that
+// matches one of the aliases of an object field
+func TestPlanDecodeAliases(t *testing.T) {
+
+	type source struct {
+		A int `schemer:"[A,B]"`
+		C string
+	}
+	type dest struct {
+		A int8
+		B int64
+		C string `schemer:"[C,Z]"`
+	}
+
+	s, err := SchemaOf(source{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = s.Encode(&buf, source{A: 12, C: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// decode into the schema's own Go type and into a type whose fields
+	// match the aliases
+	for _, expected := range []interface{}{
+		&source{A: 12, C: "c"},
+		&dest{A: 12, B: 12, C: "c"},
+	} {
+		typ := reflect.TypeOf(expected).Elem()
+
+		fromSchema := reflect.New(typ).Interface()
+		r := bytes.NewReader(buf.Bytes())
+		if err = s.Decode(r, fromSchema); err != nil {
+			t.Fatal(err)
+		}
+		if r.Len() != 0 {
+			t.Errorf("%v: %d bytes not read by DecodeValue", typ, r.Len())
+		}
+
+		p, err := Compile(s, typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fromPlan := reflect.New(typ).Interface()
+		r = bytes.NewReader(buf.Bytes())
+		if err = p.Decode(r, fromPlan); err != nil {
+			t.Fatal(err)
+		}
+		if r.Len() != 0 {
+			t.Errorf("%v: %d bytes not read by Plan", typ, r.Len())
+		}
+
+		if !reflect.DeepEqual(fromSchema, fromPlan) {
+			t.Errorf("DecodeValue returned %#v; Plan returned %#v", fromSchema, fromPlan)
+		}
+		if !reflect.DeepEqual(fromPlan, expected) {
+			t.Errorf("expected %#v; got %#v", expected, fromPlan)
+		}
+	}
+}
+
+func TestPlanNullable(t *testing.T) {
+
+	s := &VarIntSchema{Signed: true}
+	s.SetNullable(true)
+
+	p, err := Compile(s, reflect.TypeOf((*int)(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err = p.Encode(&buf, (*int)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), []byte{1}) {
+		t.Fatalf("unexpected encoding of nil: %x", buf.Bytes())
+	}
+
+	i := 5
+	decoded := &i
+	if err = p.Decode(bytes.NewReader(buf.Bytes()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != nil {
+		t.Error("expected nil pointer")
+	}
+
+	buf.Reset()
+	if err = p.Encode(&buf, &i); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Decode(bytes.NewReader(buf.Bytes()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded == nil || *decoded != 5 {
+		t.Errorf("unexpected decode %v", decoded)
+	}
+
+	// null values cannot be decoded to non-pointer types
+	p, err = Compile(s, reflect.TypeOf(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Decode(bytes.NewReader([]byte{1}), &i)
+	if err == nil {
+		t.Error("expected error decoding null to int")
+	}
+
+	// nil values cannot be encoded by non-nullable schemas
+	p, err = Compile(&VarIntSchema{}, reflect.TypeOf((*int)(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Encode(&buf, (*int)(nil)); err == nil {
+		t.Error("expected error encoding nil with non-nullable schema")
+	}
+}
+
+func benchmarkRecords() []planStruct {
+	records := make([]planStruct, 100)
+	for i := range records {
+		records[i] = newPlanStruct(i)
+		records[i].Any = nil
+		records[i].Extra = nil
+	}
+	return records
+}
+
+func BenchmarkSchemaEncode(b *testing.B) {
+	records := benchmarkRecords()
+	s, err := SchemaOf(records)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var buf bytes.Buffer
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		if err := s.Encode(&buf, records); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPlanEncode(b *testing.B) {
+	records := benchmarkRecords()
+	s, err := SchemaOf(records)
+	if err != nil {
+		b.Fatal(err)
+	}
+	p, err := Compile(s, reflect.TypeOf(records))
+	if err != nil {
+		b.Fatal(err)
+	}
+	var buf bytes.Buffer
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		if err := p.Encode(&buf, records); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSchemaDecode(b *testing.B) {
+	records := benchmarkRecords()
+	s, err := SchemaOf(records)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := s.Encode(&buf, records); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var decoded []planStruct
+		if err := s.Decode(bytes.NewReader(buf.Bytes()), &decoded); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPlanDecode(b *testing.B) {
+	records := benchmarkRecords()
+	s, err := SchemaOf(records)
+	if err != nil {
+		b.Fatal(err)
+	}
+	p, err := Compile(s, reflect.TypeOf(records))
+	if err != nil {
+		b.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := s.Encode(&buf, records); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var decoded []planStruct
+		if err := p.Decode(bytes.NewReader(buf.Bytes()), &decoded); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package schemer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"reflect"
+)
+
+// Record logs are append-only sequences of values encoded with a single
+// schema. Each record is written as an unsigned variable-size integer length,
+// the encoded value, and the CRC-32C checksum of the encoded value (4 bytes in
+// little-endian byte order). A 16-byte sync marker is written before the
+// first record written by each RecordWriter and periodically thereafter, so
+// that a RecordReader can skip damaged regions and resume reading at the next
+// sync marker.
+
+// recordSync is the sync marker of record logs
+var recordSync = []byte{
+	0xE9, 0x3B, 0x5C, 0xA1, 0x72, 0x0F, 0xD4, 0x86,
+	0x1B, 0xC7, 0x63, 0x9E, 0x28, 0xF5, 0x4A, 0xB0,
+}
+
+// MaxRecordSize is the maximum size of an encoded value in a record log.
+// Records with larger lengths are considered to be damaged.
+const MaxRecordSize = 64 << 20
+
+// DefaultSyncInterval is the default number of records between sync markers
+// written by a RecordWriter
+const DefaultSyncInterval = 64
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// RecordWriter appends values to a record log
+type RecordWriter struct {
+	w            io.Writer
+	blocks       blockWriter // each record is a block holding one value
+	syncInterval int
+	sinceSync    int // records written since the last sync marker
+	started      bool
+
+	out []byte
+}
+
+// NewRecordWriter returns a RecordWriter that appends values encoded with
+// schema s to w. w is typically a file opened with os.O_APPEND.
+func NewRecordWriter(w io.Writer, s Schema) *RecordWriter {
+	rw := &RecordWriter{
+		w:            w,
+		syncInterval: DefaultSyncInterval,
+	}
+	rw.blocks = blockWriter{
+		plans:      planCache{schema: s},
+		maxBytes:   math.MaxInt,
+		maxValues:  1,
+		writeBlock: rw.writeRecord,
+	}
+	return rw
+}
+
+// SetSyncInterval sets the number of records between sync markers. Smaller
+// intervals lose fewer records when the log is damaged.
+func (rw *RecordWriter) SetSyncInterval(n int) {
+	rw.syncInterval = n
+}
+
+// Append encodes v and writes it to the log using a single call to Write
+func (rw *RecordWriter) Append(v interface{}) error {
+	return rw.blocks.append(reflect.ValueOf(v), "RecordWriter")
+}
+
+// writeRecord writes a record holding the encoded value payload to the log
+func (rw *RecordWriter) writeRecord(_ int, payload []byte) error {
+	if len(payload) > MaxRecordSize {
+		return fmt.Errorf("record size %d exceeds maximum of %d", len(payload), MaxRecordSize)
+	}
+
+	out := rw.out[:0]
+	writeSync := !rw.started || rw.sinceSync >= rw.syncInterval
+	if writeSync {
+		out = append(out, recordSync...)
+	}
+	out = appendUvarint(out, uint64(len(payload)))
+	out = append(out, payload...)
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.Checksum(payload, crc32c))
+	out = append(out, sum[:]...)
+	rw.out = out
+
+	_, err := rw.w.Write(out)
+	if err != nil {
+		return err
+	}
+	if writeSync {
+		rw.started = true
+		rw.sinceSync = 0
+	}
+	rw.sinceSync++
+	return nil
+}
+
+// SkippedRegion describes a damaged region of a record log that was skipped by
+// a RecordReader in recovery mode
+type SkippedRegion struct {
+	Offset int64 // offset of the first skipped byte
+	Length int64 // number of bytes skipped
+	Err    error // error that caused the region to be skipped
+}
+
+func (s SkippedRegion) String() string {
+	return fmt.Sprintf("skipped %d bytes at offset %d: %v", s.Length, s.Offset, s.Err)
+}
+
+// RecordReader reads values from a record log. It is used in the same way as a
+// FileReader.
+// By default, reading stops at the first damaged record. In recovery mode
+// (see Recover), damaged regions are skipped and reading continues at the
+// next sync marker.
+type RecordReader struct {
+	r      io.Reader
+	blocks blockReader // each record is a block holding one value
+
+	recover bool
+	report  func(SkippedRegion)
+
+	buf     []byte // unread input
+	off     int64  // offset of buf[0]
+	eof     bool   // r returned io.EOF
+	readErr error  // r returned an error other than io.EOF
+}
+
+// NewRecordReader returns a RecordReader that reads values encoded with schema
+// s from r
+func NewRecordReader(r io.Reader, s Schema) *RecordReader {
+	rr := &RecordReader{r: r}
+	rr.blocks = blockReader{
+		plans:     planCache{schema: s},
+		readBlock: rr.readRecord,
+		single:    true,
+	}
+	return rr
+}
+
+// Recover enables recovery mode. Damaged or truncated records are skipped, and
+// report (if not nil) is called for each skipped region.
+func (rr *RecordReader) Recover(report func(SkippedRegion)) {
+	rr.recover = true
+	rr.report = report
+}
+
+// Offset returns the offset of the next unread byte of the log. The record
+// prepared by Next has already been read.
+func (rr *RecordReader) Offset() int64 {
+	return rr.off
+}
+
+// fill reads from r until at least n bytes are buffered, r returns io.EOF, or
+// r returns an error
+func (rr *RecordReader) fill(n int) error {
+	for len(rr.buf) < n && !rr.eof && rr.readErr == nil {
+		if cap(rr.buf)-len(rr.buf) < 512 {
+			// Grow the buffer as data arrives, so that a damaged record
+			// length does not cause a large allocation before the record
+			// is checked
+			grow := n - len(rr.buf)
+			if grow > maxPrealloc {
+				grow = maxPrealloc
+			}
+			if grow < cap(rr.buf) {
+				grow = cap(rr.buf)
+			}
+			if grow < 4096 {
+				grow = 4096
+			}
+			buf := make([]byte, len(rr.buf), len(rr.buf)+grow)
+			copy(buf, rr.buf)
+			rr.buf = buf
+		}
+		m, err := rr.r.Read(rr.buf[len(rr.buf):cap(rr.buf)])
+		rr.buf = rr.buf[:len(rr.buf)+m]
+		if err == io.EOF {
+			rr.eof = true
+		} else if err != nil {
+			rr.readErr = err
+		}
+	}
+	return rr.readErr
+}
+
+// discard removes the first n bytes from the buffer
+func (rr *RecordReader) discard(n int) {
+	rr.buf = rr.buf[n:]
+	rr.off += int64(n)
+}
+
+// Next prepares the next value for reading with Decode. It returns false when
+// there are no more values or an error occurs; use Err to check for errors.
+func (rr *RecordReader) Next() bool {
+	return rr.blocks.next()
+}
+
+// readRecord reads the next record, skipping sync markers and, in recovery
+// mode, damaged regions. The record is removed from the buffer, and its
+// encoded value is returned.
+func (rr *RecordReader) readRecord() (uint64, []byte, error) {
+	for {
+		if err := rr.fill(len(recordSync)); err != nil {
+			return 0, nil, err
+		}
+		if len(rr.buf) == 0 {
+			return 0, nil, io.EOF
+		}
+		if bytes.HasPrefix(rr.buf, recordSync) {
+			rr.discard(len(recordSync))
+			continue
+		}
+
+		n, err := rr.parseRecord()
+		if rr.readErr != nil {
+			return 0, nil, rr.readErr
+		}
+		if err == nil {
+			// Later reads do not overwrite the discarded record
+			record := rr.buf[:n:n]
+			rr.discard(n)
+			return 1, record, nil
+		}
+		if !rr.recover {
+			return 0, nil, fmt.Errorf("record at offset %d: %w", rr.off, err)
+		}
+
+		region := SkippedRegion{Offset: rr.off, Err: err}
+		region.Length, err = rr.resync()
+		if rr.report != nil {
+			rr.report(region)
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+// parseRecord checks the record at the start of the buffer. If the record is
+// valid, the length and checksum are removed from the buffer, and the size of
+// the encoded value (which is now at the start of the buffer) is returned.
+func (rr *RecordReader) parseRecord() (int, error) {
+	rr.fill(binary.MaxVarintLen64)
+	length, k := binary.Uvarint(rr.buf)
+	if k == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if k < 0 || length > MaxRecordSize {
+		return 0, fmt.Errorf("invalid record length")
+	}
+
+	n := int(length)
+	rr.fill(k + n + 4)
+	if len(rr.buf) < k+n+4 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	payload := rr.buf[k : k+n]
+	sum := binary.LittleEndian.Uint32(rr.buf[k+n:])
+	if crc32.Checksum(payload, crc32c) != sum {
+		return 0, ErrChecksumMismatch
+	}
+
+	// Move the encoded value to the end of the record, so that the entire
+	// record is discarded when the value has been read
+	copy(rr.buf[k+4:], payload)
+	rr.discard(k + 4)
+	return n, nil
+}
+
+// resync discards bytes until the next sync marker and returns the number of
+// bytes discarded. At least 1 byte is discarded.
+func (rr *RecordReader) resync() (int64, error) {
+	skipped := int64(1)
+	rr.discard(1)
+	for {
+		if i := bytes.Index(rr.buf, recordSync); i >= 0 {
+			rr.discard(i)
+			return skipped + int64(i), nil
+		}
+		if rr.eof {
+			n := len(rr.buf)
+			rr.discard(n)
+			return skipped + int64(n), nil
+		}
+		// Keep bytes that may be the start of a sync marker
+		if n := len(rr.buf) - (len(recordSync) - 1); n > 0 {
+			rr.discard(n)
+			skipped += int64(n)
+		}
+		if err := rr.fill(len(rr.buf) + 4096); err != nil {
+			return skipped, err
+		}
+	}
+}
+
+// Decode reads the next value from the log and stores it in i, which must be
+// a non-nil pointer. io.EOF is returned if there are no more values.
+func (rr *RecordReader) Decode(i interface{}) error {
+	return rr.DecodeValue(reflect.ValueOf(i))
+}
+
+// DecodeValue reads the next value from the log and stores it in v, which must
+// be a non-nil pointer. io.EOF is returned if there are no more values.
+func (rr *RecordReader) DecodeValue(v reflect.Value) error {
+	return rr.blocks.decodeValue(v)
+}
+
+// Err returns the first error encountered by Next, other than io.EOF
+func (rr *RecordReader) Err() error {
+	return rr.blocks.errValue()
+}
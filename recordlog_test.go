@@ -0,0 +1,137 @@
+package schemer
+
+import (
+	"bytes"
+	"testing"
+)
+
+// writeRecords appends records first..last-1 to a new log session
+func writeRecords(t *testing.T, buf *bytes.Buffer, first, last int) {
+	t.Helper()
+	rw := NewRecordWriter(buf, &VarIntSchema{Signed: true})
+	rw.SetSyncInterval(10)
+	for i := first; i < last; i++ {
+		if err := rw.Append(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readRecords reads all records from data
+func readRecords(data []byte, recover bool) ([]int, []SkippedRegion, error) {
+	rr := NewRecordReader(bytes.NewReader(data), &VarIntSchema{Signed: true})
+	var skipped []SkippedRegion
+	if recover {
+		rr.Recover(func(s SkippedRegion) {
+			skipped = append(skipped, s)
+		})
+	}
+	var values []int
+	for rr.Next() {
+		var i int
+		if err := rr.Decode(&i); err != nil {
+			return values, skipped, err
+		}
+		values = append(values, i)
+	}
+	return values, skipped, rr.Err()
+}
+
+func TestRecordLog(t *testing.T) {
+
+	var buf bytes.Buffer
+	writeRecords(t, &buf, 0, 100)
+	data := buf.Bytes()
+
+	values, _, err := readRecords(data, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 100 || values[99] != 99 {
+		t.Fatalf("unexpected values %v", values)
+	}
+
+	// corrupt the checksum of record 25; records 20-29 follow the same sync
+	// marker, so records 25-29 are lost
+	// Note: values less than 64 are encoded as 1 byte
+	recordSize := 1 + 1 + 4 // length, value, checksum
+	pos := 3*len(recordSync) + 25*recordSize + recordSize - 1
+	data[pos] ^= 0xFF
+
+	_, _, err = readRecords(data, false)
+	if err == nil {
+		t.Error("expected error reading damaged record")
+	}
+
+	values, skipped, err := readRecords(data, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 95 || values[24] != 24 || values[25] != 30 {
+		t.Errorf("unexpected values %v", values)
+	}
+	if len(skipped) != 1 {
+		t.Fatalf("unexpected skipped regions %v", skipped)
+	}
+	if skipped[0].Offset != int64(pos-recordSize+1) ||
+		skipped[0].Length != int64(5*recordSize) ||
+		skipped[0].Err != ErrChecksumMismatch {
+		t.Errorf("unexpected skipped region %v", skipped[0])
+	}
+}
+
+func TestRecordLogTruncated(t *testing.T) {
+
+	var buf bytes.Buffer
+	writeRecords(t, &buf, 0, 15)
+	buf.Truncate(buf.Len() - 2)
+	truncatedLen := buf.Len()
+
+	// a new session appends to the truncated log
+	writeRecords(t, &buf, 100, 105)
+	data := buf.Bytes()
+
+	_, _, err := readRecords(data, false)
+	if err == nil {
+		t.Error("expected error reading truncated record")
+	}
+
+	values, skipped, err := readRecords(data, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 19 || values[13] != 13 || values[14] != 100 {
+		t.Errorf("unexpected values %v", values)
+	}
+	if len(skipped) != 1 || skipped[0].Offset+skipped[0].Length != int64(truncatedLen) {
+		t.Errorf("unexpected skipped regions %v", skipped)
+	}
+
+	// truncated at the end of the log
+	values, skipped, err = readRecords(data[:len(data)-1], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 18 || len(skipped) != 2 {
+		t.Errorf("unexpected values %v and skipped regions %v", values, skipped)
+	}
+}
+
+// a damaged record length must not cause a large allocation
+func TestRecordLogLength(t *testing.T) {
+
+	data := append([]byte(nil), recordSync...)
+	data = appendUvarint(data, MaxRecordSize)
+	data = append(data, make([]byte, 100)...)
+
+	rr := NewRecordReader(bytes.NewReader(data), &VarIntSchema{Signed: true})
+	if rr.Next() {
+		t.Fatal("expected error reading truncated record")
+	}
+	if rr.Err() == nil {
+		t.Error("expected error reading truncated record")
+	}
+	if cap(rr.buf) > 2*maxPrealloc {
+		t.Errorf("unexpected buffer size %d", cap(rr.buf))
+	}
+}
@@ -0,0 +1,260 @@
+package schemer
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Registry holds a set of schema generators and uses them to generate and
+// decode schemas (see SchemaGenerator). A Registry is safe for concurrent use
+// by multiple goroutines.
+// The zero value is an empty Registry with no schema generators; use
+// NewRegistry to create a Registry with the built-in schema generators (i.e.
+// for time.Time and net.IP).
+type Registry struct {
+	mu      sync.RWMutex
+	entries []registryEntry
+	nextSeq uint64
+
+	// gens is rebuilt whenever entries changes and is never modified in place,
+	// so that it can be read without holding mu while schema generators are
+	// running (generators may call back into the Registry)
+	gens *registryGenerators
+
+	// schemas stores the large schemas of variants and schema values (see
+	// SetSchemaRegistry)
+	schemas SchemaRegistry
+}
+
+// registryEntry is a schema generator recorded by a Registry
+type registryEntry struct {
+	sg       interface{}
+	priority int
+	seq      uint64
+}
+
+// registryGenerators is a snapshot of the schema generators of a Registry
+type registryGenerators struct {
+	schemaOfType     []hasSchemaOfType
+	decodeSchema     []hasDecodeSchema
+	decodeSchemaJSON []hasDecodeSchemaJSON
+	customTypes      map[UUID]CustomTypeGenerator
+
+	// cache maps reflect.Type to the Schema built by SchemaOfType using these
+	// schema generators
+	cache *sync.Map
+
+	// codecs maps reflect.Type to the *Codec[T] used by Marshal and Unmarshal
+	codecs sync.Map
+}
+
+// DefaultRegistry is the Registry used by the package-level SchemaOf,
+// SchemaOfType, DecodeSchema, DecodeSchemaJSON, and Register functions.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry returns a new Registry containing the built-in schema generators
+func NewRegistry() *Registry {
+	reg := &Registry{}
+	reg.Register(dateSchemaGenerator{}, ipv4SchemaGenerator{})
+	return reg
+}
+
+// generators returns the current snapshot of the registry's schema generators
+func (reg *Registry) generators() *registryGenerators {
+	reg.mu.RLock()
+	gens := reg.gens
+	reg.mu.RUnlock()
+	if gens != nil {
+		return gens
+	}
+
+	// The zero value gets its own empty snapshot, since the schema cache
+	// holds schemas that refer to the Registry that built them
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.gens == nil {
+		reg.rebuild()
+	}
+	return reg.gens
+}
+
+// Register records custom schema generators that implement `SchemaOfType`,
+// `DecodeSchema`, and/or `DecodeSchemaJSON` with a priority of 0.
+// See RegisterPriority.
+func (reg *Registry) Register(ifaces ...interface{}) error {
+	return reg.RegisterPriority(0, ifaces...)
+}
+
+// RegisterPriority records custom schema generators that implement
+// `SchemaOfType`, `DecodeSchema`, and/or `DecodeSchemaJSON`. Schema generators
+// with a higher priority are called before those with a lower priority; schema
+// generators with the same priority are called in the order they were
+// registered.
+// Schema generators that implement CustomTypeGenerator are also recorded by
+// UUID; an error is returned if a UUID is already registered, in which case
+// none of the schema generators are registered.
+func (reg *Registry) RegisterPriority(priority int, ifaces ...interface{}) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	ids := make(map[UUID]bool, len(ifaces))
+	for _, e := range reg.entries {
+		if sg, ok := e.sg.(CustomTypeGenerator); ok {
+			ids[sg.CustomTypeUUID()] = true
+		}
+	}
+	for _, iface := range ifaces {
+		if sg, ok := iface.(CustomTypeGenerator); ok {
+			id := sg.CustomTypeUUID()
+			if ids[id] {
+				return fmt.Errorf("custom type %v is already registered", id)
+			}
+			if _, ok := schemaRefID(id); ok {
+				return fmt.Errorf("custom type %v is reserved for schema references", id)
+			}
+			ids[id] = true
+		}
+	}
+
+	for _, iface := range ifaces {
+		reg.entries = append(reg.entries, registryEntry{
+			sg:       iface,
+			priority: priority,
+			seq:      reg.nextSeq,
+		})
+		reg.nextSeq++
+	}
+	reg.rebuild()
+	return nil
+}
+
+// SetSchemaRegistry sets the SchemaRegistry used by the variants and schema
+// values of schemas created by reg (see VariantSchema and SchemaSchema).
+// When a SchemaRegistry is set, value schemas larger than 17 bytes are
+// registered with sr and written as a 17-byte reference to their ID, and
+// DecodeSchema looks up these references in sr. A nil sr disables schema
+// references.
+func (reg *Registry) SetSchemaRegistry(sr SchemaRegistry) {
+	reg.mu.Lock()
+	reg.schemas = sr
+	reg.mu.Unlock()
+}
+
+// SchemaRegistry returns the SchemaRegistry set by SetSchemaRegistry, or nil
+func (reg *Registry) SchemaRegistry() SchemaRegistry {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.schemas
+}
+
+// Unregister removes schema generators previously passed to Register or
+// RegisterPriority. Schema generators are compared using ==; generators that
+// are not comparable can only be unregistered if they are pointers.
+// Schema generators that were never registered are ignored.
+func (reg *Registry) Unregister(ifaces ...interface{}) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	entries := reg.entries[:0]
+	for _, e := range reg.entries {
+		if !containsGenerator(ifaces, e.sg) {
+			entries = append(entries, e)
+		}
+	}
+	for i := len(entries); i < len(reg.entries); i++ {
+		reg.entries[i] = registryEntry{}
+	}
+	reg.entries = entries
+	reg.rebuild()
+}
+
+// containsGenerator returns true if sg is in ifaces
+func containsGenerator(ifaces []interface{}, sg interface{}) bool {
+	t := reflect.TypeOf(sg)
+	if t == nil || !t.Comparable() {
+		return false
+	}
+	for _, iface := range ifaces {
+		if reflect.TypeOf(iface) == t && iface == sg {
+			return true
+		}
+	}
+	return false
+}
+
+// rebuild sorts the registry entries by priority and builds a new snapshot of
+// the schema generators with an empty schema cache. reg.mu must be held for
+// writing.
+func (reg *Registry) rebuild() {
+	sort.Slice(reg.entries, func(i, j int) bool {
+		if reg.entries[i].priority != reg.entries[j].priority {
+			return reg.entries[i].priority > reg.entries[j].priority
+		}
+		return reg.entries[i].seq < reg.entries[j].seq
+	})
+
+	gens := &registryGenerators{
+		customTypes: map[UUID]CustomTypeGenerator{},
+		cache:       &sync.Map{},
+	}
+	for _, e := range reg.entries {
+		if sg, ok := e.sg.(CustomTypeGenerator); ok {
+			gens.customTypes[sg.CustomTypeUUID()] = sg
+		}
+		if sg, ok := e.sg.(hasSchemaOfType); ok {
+			gens.schemaOfType = append(gens.schemaOfType, sg)
+		}
+		if sg, ok := e.sg.(hasDecodeSchema); ok {
+			gens.decodeSchema = append(gens.decodeSchema, sg)
+		}
+		if sg, ok := e.sg.(hasDecodeSchemaJSON); ok {
+			gens.decodeSchemaJSON = append(gens.decodeSchemaJSON, sg)
+		}
+	}
+	reg.gens = gens
+}
+
+// Register records custom schema generators with DefaultRegistry.
+// See Registry.Register.
+func Register(ifaces ...interface{}) error {
+	return DefaultRegistry.Register(ifaces...)
+}
+
+// RegisterPriority records custom schema generators with DefaultRegistry.
+// See Registry.RegisterPriority.
+func RegisterPriority(priority int, ifaces ...interface{}) error {
+	return DefaultRegistry.RegisterPriority(priority, ifaces...)
+}
+
+// Unregister removes schema generators from DefaultRegistry.
+// See Registry.Unregister.
+func Unregister(ifaces ...interface{}) {
+	DefaultRegistry.Unregister(ifaces...)
+}
+
+// SchemaOf returns a Schema for the specified interface value using
+// DefaultRegistry. See Registry.SchemaOf.
+func SchemaOf(i interface{}) (Schema, error) {
+	return DefaultRegistry.SchemaOf(i)
+}
+
+// SchemaOfType returns a Schema for the specified Go type using
+// DefaultRegistry. See Registry.SchemaOfType.
+func SchemaOfType(t reflect.Type) (Schema, error) {
+	return DefaultRegistry.SchemaOfType(t)
+}
+
+// DecodeSchema decodes a binary encoded schema by reading from r using
+// DefaultRegistry. See Registry.DecodeSchema.
+func DecodeSchema(r io.Reader) (Schema, error) {
+	return DefaultRegistry.DecodeSchema(r)
+}
+
+// DecodeSchemaJSON decodes a JSON encoded schema by reading from r using
+// DefaultRegistry. See Registry.DecodeSchemaJSON.
+func DecodeSchemaJSON(r io.Reader) (Schema, error) {
+	return DefaultRegistry.DecodeSchemaJSON(r)
+}
@@ -0,0 +1,291 @@
+package schemer
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fixedStringGenerator returns a FixedStringSchema of the specified length for
+// all string types
+type fixedStringGenerator struct {
+	length int
+}
+
+func (sg *fixedStringGenerator) SchemaOfType(t reflect.Type) (Schema, error) {
+	if t.Kind() != reflect.String {
+		return nil, nil
+	}
+	return &FixedStringSchema{Length: sg.length}, nil
+}
+
+func (sg *fixedStringGenerator) DecodeSchema(r io.Reader) (Schema, error) {
+	return nil, nil
+}
+
+func (sg *fixedStringGenerator) DecodeSchemaJSON(r io.Reader) (Schema, error) {
+	return nil, nil
+}
+
+func stringSchemaLength(t *testing.T, reg *Registry) int {
+	s, err := reg.SchemaOf("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixedStringSchema, ok := s.(*FixedStringSchema); ok {
+		return fixedStringSchema.Length
+	}
+	return -1
+}
+
+func TestRegistryIsolation(t *testing.T) {
+
+	reg := NewRegistry()
+	err := reg.Register(&fixedStringGenerator{length: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stringSchemaLength(t, reg) != 4 {
+		t.Error("expected registered generator to be used")
+	}
+	if stringSchemaLength(t, NewRegistry()) != -1 {
+		t.Error("generator leaked to another registry")
+	}
+	if stringSchemaLength(t, DefaultRegistry) != -1 {
+		t.Error("generator leaked to DefaultRegistry")
+	}
+
+	// built-in generators are included in new registries
+	s, err := reg.SchemaOf(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(*DateSchema); !ok {
+		t.Errorf("expected DateSchema; got %T", s)
+	}
+
+	// the zero value has no generators
+	var empty Registry
+	s, err = empty.SchemaOf(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(*DateSchema); ok {
+		t.Error("expected zero Registry to have no generators")
+	}
+}
+
+// TestRegistryZeroValues checks that zero-value registries do not share
+// cached schemas
+func TestRegistryZeroValues(t *testing.T) {
+
+	type dynamic struct {
+		V interface{}
+	}
+	var a, b Registry
+	for _, reg := range []*Registry{&a, &b, &a} {
+		s, err := reg.SchemaOf(dynamic{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		variant := s.(*FixedObjectSchema).Fields[0].Schema.(*VariantSchema)
+		if variant.registry() != reg {
+			t.Error("expected variant to use the registry that built it")
+		}
+	}
+	if a.generators() == b.generators() {
+		t.Error("expected zero-value registries to have separate generators")
+	}
+}
+
+func TestRegistryPriority(t *testing.T) {
+
+	reg := NewRegistry()
+	low := &fixedStringGenerator{length: 1}
+	high := &fixedStringGenerator{length: 2}
+	same := &fixedStringGenerator{length: 3}
+
+	if err := reg.RegisterPriority(-1, low); err != nil {
+		t.Fatal(err)
+	}
+	if stringSchemaLength(t, reg) != 1 {
+		t.Error("expected low priority generator")
+	}
+	if err := reg.RegisterPriority(10, high); err != nil {
+		t.Fatal(err)
+	}
+	if stringSchemaLength(t, reg) != 2 {
+		t.Error("expected high priority generator")
+	}
+	// registration order is used for generators with the same priority
+	if err := reg.RegisterPriority(10, same); err != nil {
+		t.Fatal(err)
+	}
+	if stringSchemaLength(t, reg) != 2 {
+		t.Error("expected first registered generator")
+	}
+
+	reg.Unregister(high)
+	if stringSchemaLength(t, reg) != 3 {
+		t.Error("expected generator to be unregistered")
+	}
+	reg.Unregister(same, low)
+	if stringSchemaLength(t, reg) != -1 {
+		t.Error("expected all generators to be unregistered")
+	}
+
+	// unregistered generators are ignored
+	reg.Unregister(high, fixedStringGenerator{})
+}
+
+func TestRegistryCustomTypes(t *testing.T) {
+
+	reg := NewRegistry()
+	id := UUID{0x07, 0x08, 0x09}
+	sg := durationSchemaGenerator{id: id}
+
+	s := &durationSchema{VarIntSchema: VarIntSchema{Signed: true}, id: id}
+	b, err := s.MarshalSchemer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = reg.Register(sg); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := reg.DecodeSchema(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.(*durationSchema); !ok {
+		t.Fatalf("unexpected decoded schema %#v", decoded)
+	}
+
+	// the custom type is unknown to other registries
+	decoded, err = DecodeSchema(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.(*VarIntSchema); !ok {
+		t.Fatalf("unexpected decoded schema %#v", decoded)
+	}
+
+	// the UUID can be registered again once it is unregistered
+	reg.Unregister(sg)
+	if err = reg.Register(sg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRegistryDynamicSchemas checks that variants and schema values use the
+// Registry that created them
+func TestRegistryDynamicSchemas(t *testing.T) {
+
+	reg := NewRegistry()
+	id := UUID{0x0A, 0x0B, 0x0C}
+	err := reg.Register(&fixedStringGenerator{length: 3}, durationSchemaGenerator{id: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type dynamic struct {
+		V interface{}
+		S Schema
+	}
+	s, err := reg.SchemaOf(dynamic{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	variant := s.(*FixedObjectSchema).Fields[0].Schema
+	schema := s.(*FixedObjectSchema).Fields[1].Schema
+
+	// variant values are encoded with the registry's generators
+	var buf bytes.Buffer
+	if err = variant.Encode(&buf, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if b := buf.Bytes(); len(b) < 2 || b[1]&^NullMask != FixedStringByte {
+		t.Errorf("expected fixed-length string schema; got %v", b)
+	}
+	var i interface{}
+	if err = variant.Decode(&buf, &i); err != nil {
+		t.Fatal(err)
+	}
+	if i != "abc" {
+		t.Errorf("unexpected value %#v", i)
+	}
+
+	// schema values are decoded with the registry's custom types
+	buf.Reset()
+	duration := &durationSchema{VarIntSchema: VarIntSchema{Signed: true}, id: id}
+	if err = schema.Encode(&buf, duration); err != nil {
+		t.Fatal(err)
+	}
+	b := append([]byte(nil), buf.Bytes()...)
+	var decoded Schema
+	if err = schema.Decode(&buf, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.(*durationSchema); !ok {
+		t.Errorf("expected *durationSchema; got %T", decoded)
+	}
+
+	// schemas decoded by the registry also use it
+	bs, err := s.(Marshaler).MarshalSchemer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = reg.DecodeSchema(bytes.NewReader(bs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema = s.(*FixedObjectSchema).Fields[1].Schema
+	if err = schema.Decode(bytes.NewReader(b), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.(*durationSchema); !ok {
+		t.Errorf("expected *durationSchema; got %T", decoded)
+	}
+
+	// other schemas use DefaultRegistry
+	if err = (&SchemaSchema{SchemaOptions: SchemaOptions{nullable: true}}).Decode(bytes.NewReader(b), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.(*VarIntSchema); !ok {
+		t.Errorf("expected *VarIntSchema; got %T", decoded)
+	}
+}
+
+func TestRegistryConcurrency(t *testing.T) {
+
+	reg := NewRegistry()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sg := &fixedStringGenerator{length: i}
+			for j := 0; j < 100; j++ {
+				if err := reg.RegisterPriority(i, sg); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := reg.SchemaOf(sourceStruct{}); err != nil {
+					t.Error(err)
+					return
+				}
+				reg.Unregister(sg)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if stringSchemaLength(t, reg) != -1 {
+		t.Error("expected all generators to be unregistered")
+	}
+}
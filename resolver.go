@@ -0,0 +1,364 @@
+package schemer
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Resolver decodes values that were encoded with a writer schema into values
+// that conform to a reader schema (i.e. values of type reader.GoType()).
+// This allows a consumer that only knows its own schema to read data written
+// with an older or newer schema without defining any Go types.
+// When resolving values:
+//   - Object fields are matched by alias; writer fields that are not in the
+//     reader schema are skipped, and reader fields that are not in the writer
+//     schema are set to their default values (see ObjectField.Default) or
+//     left as zero values.
+//   - Numbers are converted according to the type compatibility rules (see
+//     CheckCompatibility).
+//   - Enumerations are mapped by name rather than by numeric value.
+type Resolver struct {
+	writer  Schema
+	reader  Schema
+	t       reflect.Type
+	resolve resolveFunc
+}
+
+// resolveFunc reads a value encoded with a writer schema from r and stores it
+// in v, which is a settable value of the reader schema's Go type
+type resolveFunc func(r io.Reader, v reflect.Value) error
+
+// NewResolver returns a Resolver for the specified writer and reader schemas.
+// An error is returned if CheckCompatibility reports that the schemas are not
+// compatible.
+func NewResolver(writer, reader Schema) (*Resolver, error) {
+	if writer == nil || reader == nil {
+		return nil, fmt.Errorf("writer and reader schemas are required")
+	}
+	if list := CheckCompatibility(writer, reader); len(list) > 0 {
+		msgs := make([]string, len(list))
+		for i, inc := range list {
+			msgs[i] = inc.String()
+		}
+		return nil, fmt.Errorf("incompatible schemas: %s", strings.Join(msgs, "; "))
+	}
+
+	t, err := goTypeOf(reader)
+	if err != nil {
+		return nil, err
+	}
+	resolve, err := compileResolver(writer, reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{
+		writer:  writer,
+		reader:  reader,
+		t:       t,
+		resolve: resolve,
+	}, nil
+}
+
+// goTypeOf returns s.GoType(), or an error if the Go type cannot be built
+// (for example, a map whose keys are not comparable)
+func goTypeOf(s Schema) (t reflect.Type, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cannot build Go type of schema: %v", r)
+		}
+	}()
+	return s.GoType(), nil
+}
+
+// GoType returns the Go type of the resolved values (i.e. reader.GoType())
+func (res *Resolver) GoType() reflect.Type {
+	return res.t
+}
+
+// Resolve reads the next value encoded with the writer schema from r and
+// returns it as a value of the reader schema's Go type
+func (res *Resolver) Resolve(r io.Reader) (interface{}, error) {
+	v := reflect.New(res.t).Elem()
+	err := res.resolve(r, v)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// Decode reads the next value encoded with the writer schema from r and stores
+// it in i, which must be a pointer to a value of the reader schema's Go type
+func (res *Resolver) Decode(r io.Reader, i interface{}) error {
+	if i == nil {
+		return fmt.Errorf("cannot decode to nil destination")
+	}
+	return res.DecodeValue(r, reflect.ValueOf(i))
+}
+
+// DecodeValue reads the next value encoded with the writer schema from r and
+// stores it in v, which must be a settable value of the reader schema's Go
+// type or a non-nil pointer to it
+func (res *Resolver) DecodeValue(r io.Reader, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr && v.Type().Elem() == res.t && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Type() != res.t {
+		return fmt.Errorf("cannot resolve %v to %v", res.t, v.Type())
+	}
+	if !v.CanSet() {
+		return fmt.Errorf("decode destination is not settable")
+	}
+	return res.resolve(r, v)
+}
+
+// compileResolver returns a resolveFunc for the writer and reader schemas.
+// Values of variants and leaf schemas (i.e. numbers and strings) are decoded
+// using the writer schema's DecodeValue; the other schemas are resolved
+// recursively.
+func compileResolver(w, r Schema) (resolveFunc, error) {
+	if _, ok := r.(*VariantSchema); ok {
+		return w.DecodeValue, nil
+	}
+
+	var payload resolveFunc
+	switch w := w.(type) {
+	case *EnumSchema:
+		re, ok := r.(*EnumSchema)
+		if !ok || len(w.Values) == 0 || len(re.Values) == 0 {
+			// unnamed values are mapped by numeric value
+			return w.DecodeValue, nil
+		}
+		mapping := enumMapping(w, re)
+		payload = func(rd io.Reader, v reflect.Value) error {
+			n, err := ReadUvarint(rd)
+			if err != nil {
+				return err
+			}
+			mapped, ok := mapping[int(n)]
+			if !ok {
+				return fmt.Errorf("enum value %d (%q) has no match in reader schema",
+					n, w.Values[int(n)])
+			}
+			v.SetInt(int64(mapped))
+			return nil
+		}
+
+	case *VarArraySchema:
+		r, ok := r.(*VarArraySchema)
+		if !ok {
+			// arrays of integers read as binary strings
+			return w.DecodeValue, nil
+		}
+		elem, err := compileResolver(w.Element, r.Element)
+		if err != nil {
+			return nil, err
+		}
+		elemSize := minEncodedSize(w.Element)
+		payload = func(rd io.Reader, v reflect.Value) error {
+			n, err := ReadUvarint(rd)
+			if err != nil {
+				return err
+			}
+			slice, err := decodeSlice(rd, v.Type(), n, elemSize, func(v reflect.Value) error {
+				return elem(rd, v)
+			})
+			if err != nil {
+				return err
+			}
+			v.Set(slice)
+			return nil
+		}
+
+	case *FixedArraySchema:
+		r, ok := r.(*FixedArraySchema)
+		if !ok {
+			return w.DecodeValue, nil
+		}
+		elem, err := compileResolver(w.Element, r.Element)
+		if err != nil {
+			return nil, err
+		}
+		payload = func(rd io.Reader, v reflect.Value) error {
+			for i := 0; i < v.Len(); i++ {
+				if err := elem(rd, v.Index(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+	case *VarObjectSchema:
+		r := r.(*VarObjectSchema)
+		key, err := compileResolver(w.Key, r.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := compileResolver(w.Value, r.Value)
+		if err != nil {
+			return nil, err
+		}
+		entrySize := minEncodedSize(w.Key) + minEncodedSize(w.Value)
+		payload = func(rd io.Reader, v reflect.Value) error {
+			n, err := ReadUvarint(rd)
+			if err != nil {
+				return err
+			}
+			if _, err := checkLen(rd, n, entrySize); err != nil {
+				return err
+			}
+			t := v.Type()
+			v.Set(reflect.MakeMap(t))
+			for i := uint64(0); i < n; i++ {
+				k := reflect.New(t.Key()).Elem()
+				val := reflect.New(t.Elem()).Elem()
+				if err := key(rd, k); err != nil {
+					return err
+				}
+				if err := value(rd, val); err != nil {
+					return err
+				}
+				v.SetMapIndex(k, val)
+			}
+			return nil
+		}
+
+	case *FixedObjectSchema:
+		r := r.(*FixedObjectSchema)
+		fields := make([]resolveFunc, len(w.Fields))
+		populated := make(map[int]bool, len(w.Fields))
+		for i, wf := range w.Fields {
+			fields[i] = skipResolver(wf.Schema)
+			index := findObjectFieldIndex(wf.Aliases, r)
+			if index < 0 {
+				continue
+			}
+			populated[index] = true
+			field, err := compileResolver(wf.Schema, r.Fields[index].Schema)
+			if err != nil {
+				return nil, fmt.Errorf("object field %v: %w", wf.Aliases, err)
+			}
+			fields[i] = func(rd io.Reader, v reflect.Value) error {
+				return field(rd, v.Field(index))
+			}
+		}
+		// reader fields that are not in the writer schema and have default
+		// values, which are converted to the fields' types only once
+		var missing []int
+		var defaults []structField
+		for i, rf := range r.Fields {
+			if populated[i] || rf.Default == nil {
+				continue
+			}
+			t, err := goTypeOf(rf.Schema)
+			if err != nil {
+				return nil, fmt.Errorf("object field %v: %w", rf.Aliases, err)
+			}
+			def := structField{def: reflect.New(t).Elem()}
+			def.defErr = setDefault(def.def, rf.Default)
+			missing = append(missing, i)
+			defaults = append(defaults, def)
+		}
+		payload = func(rd io.Reader, v reflect.Value) error {
+			v.Set(reflect.Zero(v.Type()))
+			for _, field := range fields {
+				if err := field(rd, v); err != nil {
+					return err
+				}
+			}
+			for j, i := range missing {
+				if err := defaults[j].setDefault(v.Field(i)); err != nil {
+					return fmt.Errorf("object field %v: %w", r.Fields[i].Aliases, err)
+				}
+			}
+			return nil
+		}
+
+	default:
+		return w.DecodeValue, nil
+	}
+
+	return nullableResolver(nullable(w), payload), nil
+}
+
+// nullableResolver returns a resolveFunc that reads the null byte if the
+// writer schema is nullable and allocates pointers as needed before calling
+// payload to resolve the remainder of the value
+func nullableResolver(isNullable bool, payload resolveFunc) resolveFunc {
+	return func(rd io.Reader, v reflect.Value) error {
+		if isNullable {
+			buf := make([]byte, 1)
+			_, err := io.ReadFull(rd, buf)
+			if err != nil {
+				return err
+			}
+			if buf[0] == 1 {
+				if v.Kind() != reflect.Ptr {
+					return fmt.Errorf("cannot decode null value to a %s", v.Kind())
+				}
+				v.Set(reflect.Zero(v.Type()))
+				return nil
+			}
+		}
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		return payload(rd, v)
+	}
+}
+
+// skipResolver returns a resolveFunc that reads and discards a value encoded
+// with schema s
+func skipResolver(s Schema) resolveFunc {
+	return func(rd io.Reader, v reflect.Value) error {
+		var ignoreMe interface{}
+		return s.Decode(rd, &ignoreMe)
+	}
+}
+
+// findObjectFieldIndex returns the index of the field of r whose aliases
+// include one of the specified aliases, or -1 if there is no such field
+func findObjectFieldIndex(aliases []string, r *FixedObjectSchema) int {
+	f := findObjectField(aliases, r)
+	if f == nil {
+		return -1
+	}
+	for i := range r.Fields {
+		if &r.Fields[i] == f {
+			return i
+		}
+	}
+	return -1
+}
+
+// enumMapping maps the values of writer enum w to the values of reader enum r
+// with the same name. Names are matched case-insensitively unless several
+// reader values match, in which case a case-sensitive match is required.
+// Writer values without a matching name are left out of the mapping.
+func enumMapping(w, r *EnumSchema) map[int]int {
+	mapping := make(map[int]int, len(w.Values))
+	for wv, name := range w.Values {
+		matches := []int{}
+		for rv, rname := range r.Values {
+			if strings.EqualFold(name, rname) {
+				matches = append(matches, rv)
+			}
+		}
+		if len(matches) > 1 {
+			matches = matches[:0]
+			for rv, rname := range r.Values {
+				if name == rname {
+					matches = append(matches, rv)
+				}
+			}
+		}
+		if len(matches) == 1 {
+			mapping[wv] = matches[0]
+		}
+	}
+	return mapping
+}
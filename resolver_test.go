@@ -0,0 +1,226 @@
+package schemer
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type resolverV1 struct {
+	ID     int
+	Name   string
+	Color  int
+	Tags   []string
+	Legacy bool
+	Inner  *embeddedStruct
+}
+
+func TestResolver(t *testing.T) {
+
+	colorsV1 := &EnumSchema{Values: map[int]string{0: "red", 1: "green", 2: "blue"}}
+
+	writer, err := SchemaOf(resolverV1{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer = CloneSchema(writer)
+	writerObj := writer.(*FixedObjectSchema)
+	writerObj.Fields[2].Schema = colorsV1
+
+	// the reader schema renames Name, drops Legacy, widens ID to a float,
+	// reorders the enum, and adds a new field
+	reader := &FixedObjectSchema{
+		Fields: []ObjectField{
+			{Aliases: []string{"Added"}, Schema: &VarStringSchema{}},
+			{Aliases: []string{"FullName", "Name"}, Schema: &VarStringSchema{}},
+			{Aliases: []string{"ID"}, Schema: &FloatSchema{Bits: 64}},
+			{Aliases: []string{"Color"}, Schema: &EnumSchema{
+				Values: map[int]string{10: "BLUE", 11: "Green", 12: "Red"},
+			}},
+			{Aliases: []string{"Tags"}, Schema: writerObj.Fields[3].Schema},
+			{Aliases: []string{"Inner"}, Schema: writerObj.Fields[5].Schema},
+		},
+	}
+
+	res, err := NewResolver(writer, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = writer.Encode(&buf, resolverV1{
+		ID:     42,
+		Name:   "answer",
+		Color:  2,
+		Tags:   []string{"x", "y"},
+		Legacy: true,
+		Inner:  &embeddedStruct{Int1: 7},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := res.Resolve(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.TypeOf(decoded) != reader.GoType() {
+		t.Fatalf("unexpected type %T", decoded)
+	}
+
+	v := reflect.ValueOf(decoded)
+	if v.FieldByName("Added").String() != "" {
+		t.Error("unexpected value for added field")
+	}
+	if v.FieldByName("FullName").String() != "answer" {
+		t.Error("unexpected value for renamed field")
+	}
+	if v.FieldByName("ID").Float() != 42 {
+		t.Error("unexpected value for widened field")
+	}
+	if v.FieldByName("Color").Int() != 10 {
+		t.Errorf("expected enum to be mapped by name; got %d", v.FieldByName("Color").Int())
+	}
+	if !reflect.DeepEqual(v.FieldByName("Tags").Interface(), []string{"x", "y"}) {
+		t.Error("unexpected value for array field")
+	}
+	inner := v.FieldByName("Inner")
+	if inner.IsNil() || inner.Elem().FieldByName("Int1").Int() != 7 {
+		t.Error("unexpected value for nested object")
+	}
+
+	// decode into an existing value
+	dest := reflect.New(reader.GoType())
+	dest.Elem().FieldByName("Added").SetString("stale")
+	err = res.DecodeValue(bytes.NewReader(buf.Bytes()), dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dest.Elem().FieldByName("Added").String() != "" {
+		t.Error("expected missing field to be reset")
+	}
+
+	// enum values without a matching name cannot be resolved
+	delete(reader.Fields[3].Schema.(*EnumSchema).Values, 10)
+	res, err = NewResolver(writer, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = res.Resolve(bytes.NewReader(buf.Bytes()))
+	if err == nil {
+		t.Error("expected error resolving unmatched enum value")
+	}
+}
+
+func TestResolverIncompatible(t *testing.T) {
+
+	_, err := NewResolver(&VarStringSchema{}, &VarArraySchema{Element: &BoolSchema{}})
+	if err == nil {
+		t.Error("expected error creating resolver for incompatible schemas")
+	}
+
+	// nullable values are resolved to pointers
+	writer := &VarIntSchema{}
+	writer.SetNullable(true)
+	reader := &FloatSchema{Bits: 32}
+	reader.SetNullable(true)
+	res, err := NewResolver(writer, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, value := range []*int{nil, new(int)} {
+		var buf bytes.Buffer
+		if err = writer.Encode(&buf, value); err != nil {
+			t.Fatal(err)
+		}
+		var decoded *float32
+		if err = res.Decode(bytes.NewReader(buf.Bytes()), &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if (value == nil) != (decoded == nil) {
+			t.Errorf("expected %v; got %v", value, decoded)
+		}
+	}
+}
+
+// TestResolverFieldNames checks that readers whose field names are not
+// exported Go identifiers (e.g. schemas written in JSON) can be resolved
+func TestResolverFieldNames(t *testing.T) {
+
+	type person struct {
+		FirstName string `schemer:"firstName"`
+		LastName  string `schemer:"last_name"`
+		Age       int    `schemer:"age"`
+	}
+	writer, err := SchemaOf(person{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := DecodeSchemaJSON(strings.NewReader(`{
+		"type": "object",
+		"fields": [
+			{"name": ["firstName"], "type": "string"},
+			{"name": ["last-name", "last_name"], "type": "string"},
+			{"name": ["age"], "type": "int", "signed": true},
+			{"name": ["Age"], "type": "bool"},
+			{"name": ["2nd"], "type": "bool"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := NewResolver(writer, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	value := person{FirstName: "Ada", LastName: "Lovelace", Age: 36}
+	if err = writer.Encode(&buf, value); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := res.Resolve(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := reflect.ValueOf(decoded)
+	names := []string{"FirstName", "Last_name", "Age", "Age_2", "X2nd"}
+	for i, name := range names {
+		if f := v.Type().Field(i); f.Name != name {
+			t.Errorf("expected field %d to be named %s; got %s", i, name, f.Name)
+		}
+	}
+	if v.Field(0).String() != "Ada" || v.Field(1).String() != "Lovelace" || v.Field(2).Int() != 36 {
+		t.Errorf("unexpected value %+v", decoded)
+	}
+
+	// aliases are kept in struct tags
+	tag := ParseStructTag(v.Type().Field(1).Tag.Get(StructTagName))
+	if !reflect.DeepEqual(tag.FieldAliases, []string{"last_name"}) {
+		t.Errorf("unexpected aliases %v", tag.FieldAliases)
+	}
+
+	// values of the reader's Go type can be encoded and decoded
+	buf.Reset()
+	if err = reader.Encode(&buf, decoded); err != nil {
+		t.Fatal(err)
+	}
+	var i interface{}
+	if err = reader.Decode(&buf, &i); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reflect.ValueOf(i).Elem().Interface(), decoded) {
+		t.Errorf("expected %+v; got %+v", decoded, i)
+	}
+
+	// readers whose Go type cannot be built are rejected
+	m := &VarObjectSchema{Key: &VarArraySchema{Element: &BoolSchema{}}, Value: &BoolSchema{}}
+	_, err = NewResolver(m, m)
+	if err == nil {
+		t.Error("expected error creating resolver for reader without a Go type")
+	}
+}
@@ -0,0 +1,130 @@
+package schemer
+
+import (
+	"bufio"
+	"io"
+	"net/rpc"
+)
+
+// rpcRequestHeader is the encoded form of rpc.Request
+type rpcRequestHeader struct {
+	ServiceMethod string
+	Seq           uint64
+}
+
+// rpcResponseHeader is the encoded form of rpc.Response
+type rpcResponseHeader struct {
+	ServiceMethod string
+	Seq           uint64
+	Error         string
+}
+
+// rpcCodec implements rpc.ServerCodec and rpc.ClientCodec. Headers and bodies
+// are written to a stream using an Encoder, so the schema of each header and
+// argument type is only sent once per connection (see NewEncoder).
+type rpcCodec struct {
+	conn io.ReadWriteCloser
+	buf  *bufio.Writer
+	enc  *Encoder
+	dec  *Decoder
+}
+
+func newRPCCodec(conn io.ReadWriteCloser) *rpcCodec {
+	buf := bufio.NewWriter(conn)
+	return &rpcCodec{
+		conn: conn,
+		buf:  buf,
+		enc:  NewEncoder(buf),
+		dec:  NewDecoder(conn),
+	}
+}
+
+// NewRPCServerCodec returns an rpc.ServerCodec that uses schemer to
+// communicate with the client on the other end of the connection. Use
+// rpc.ServeCodec to serve requests using the codec.
+func NewRPCServerCodec(conn io.ReadWriteCloser) rpc.ServerCodec {
+	return newRPCCodec(conn)
+}
+
+// NewRPCClientCodec returns an rpc.ClientCodec that uses schemer to
+// communicate with the server on the other end of the connection. Use
+// rpc.NewClientWithCodec to create a client using the codec.
+func NewRPCClientCodec(conn io.ReadWriteCloser) rpc.ClientCodec {
+	return newRPCCodec(conn)
+}
+
+func (c *rpcCodec) ReadRequestHeader(r *rpc.Request) error {
+	var h rpcRequestHeader
+	err := c.dec.Decode(&h)
+	if err != nil {
+		return err
+	}
+	r.ServiceMethod = h.ServiceMethod
+	r.Seq = h.Seq
+	return nil
+}
+
+func (c *rpcCodec) ReadRequestBody(body interface{}) error {
+	return c.readBody(body)
+}
+
+func (c *rpcCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	h := rpcResponseHeader{
+		ServiceMethod: r.ServiceMethod,
+		Seq:           r.Seq,
+		Error:         r.Error,
+	}
+	return c.write(h, body)
+}
+
+func (c *rpcCodec) WriteRequest(r *rpc.Request, body interface{}) error {
+	h := rpcRequestHeader{
+		ServiceMethod: r.ServiceMethod,
+		Seq:           r.Seq,
+	}
+	return c.write(h, body)
+}
+
+func (c *rpcCodec) ReadResponseHeader(r *rpc.Response) error {
+	var h rpcResponseHeader
+	err := c.dec.Decode(&h)
+	if err != nil {
+		return err
+	}
+	r.ServiceMethod = h.ServiceMethod
+	r.Seq = h.Seq
+	r.Error = h.Error
+	return nil
+}
+
+func (c *rpcCodec) ReadResponseBody(body interface{}) error {
+	return c.readBody(body)
+}
+
+// readBody decodes the body of a request or response. If body is nil, the
+// body is read and discarded.
+func (c *rpcCodec) readBody(body interface{}) error {
+	if body == nil {
+		var ignoreMe interface{}
+		return c.dec.Decode(&ignoreMe)
+	}
+	return c.dec.Decode(body)
+}
+
+// write encodes a header and body and flushes them to the connection
+func (c *rpcCodec) write(header, body interface{}) error {
+	err := c.enc.Encode(header)
+	if err == nil {
+		err = c.enc.Encode(body)
+	}
+	if err != nil {
+		// The stream cannot be recovered after a partial write
+		c.conn.Close()
+		return err
+	}
+	return c.buf.Flush()
+}
+
+func (c *rpcCodec) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,91 @@
+package schemer
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"strings"
+	"testing"
+)
+
+type RPCArgs struct {
+	A, B int
+}
+
+type RPCQuotient struct {
+	Quo, Rem int
+}
+
+type RPCArith int
+
+func (t *RPCArith) Multiply(args *RPCArgs, reply *int) error {
+	*reply = args.A * args.B
+	return nil
+}
+
+func (t *RPCArith) Divide(args *RPCArgs, quo *RPCQuotient) error {
+	if args.B == 0 {
+		return errors.New("divide by zero")
+	}
+	quo.Quo = args.A / args.B
+	quo.Rem = args.A % args.B
+	return nil
+}
+
+func (t *RPCArith) Upper(s string, reply *string) error {
+	*reply = strings.ToUpper(s)
+	return nil
+}
+
+func TestRPC(t *testing.T) {
+
+	server := rpc.NewServer()
+	if err := server.Register(new(RPCArith)); err != nil {
+		t.Fatal(err)
+	}
+
+	clientConn, serverConn := net.Pipe()
+	go server.ServeCodec(NewRPCServerCodec(serverConn))
+	client := rpc.NewClientWithCodec(NewRPCClientCodec(clientConn))
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		var product int
+		err := client.Call("RPCArith.Multiply", &RPCArgs{7, 8 + i}, &product)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if product != 7*(8+i) {
+			t.Errorf("unexpected product %d", product)
+		}
+	}
+
+	var quo RPCQuotient
+	if err := client.Call("RPCArith.Divide", RPCArgs{17, 5}, &quo); err != nil {
+		t.Fatal(err)
+	}
+	if quo.Quo != 3 || quo.Rem != 2 {
+		t.Errorf("unexpected quotient %v", quo)
+	}
+
+	err := client.Call("RPCArith.Divide", RPCArgs{1, 0}, &quo)
+	if err == nil || err.Error() != "divide by zero" {
+		t.Errorf("expected divide by zero error; got %v", err)
+	}
+	err = client.Call("RPCArith.Unknown", RPCArgs{}, &quo)
+	if err == nil {
+		t.Error("expected error calling unknown method")
+	}
+
+	// concurrent calls
+	calls := make([]*rpc.Call, 10)
+	for i := range calls {
+		calls[i] = client.Go("RPCArith.Upper", "hello", new(string), nil)
+	}
+	for _, call := range calls {
+		<-call.Done
+		if call.Error != nil || *call.Reply.(*string) != "HELLO" {
+			t.Errorf("unexpected reply %v, %v", *call.Reply.(*string), call.Error)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package schemer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrSchemaNotFound is returned by a SchemaRegistry when a schema cannot be
+// found
+var ErrSchemaNotFound = errors.New("schema not found")
+
+// SchemaRegistry stores schemas and assigns each distinct schema a numeric ID,
+// so that producers and consumers of encoded data can share schemas by ID
+// rather than writing the entire schema alongside the data.
+// Schemas are considered the same if they have the same canonical form (see
+// CanonicalForm). Implementations must be safe for concurrent use by multiple
+// goroutines.
+type SchemaRegistry interface {
+	// Register adds s to the registry if an equivalent schema has not already
+	// been registered and returns the ID of the schema.
+	Register(s Schema) (uint64, error)
+
+	// Lookup returns the schema with the specified ID
+	Lookup(id uint64) (Schema, error)
+
+	// LookupFingerprint returns the ID and schema whose 64-bit fingerprint
+	// matches fp (see Fingerprint64)
+	LookupFingerprint(fp uint64) (uint64, Schema, error)
+}
+
+// A schema reference is written by variants and schema values in place of a
+// schema larger than maxInlineSchemaLen bytes when a SchemaRegistry is set
+// (see Registry.SetSchemaRegistry). It is encoded as a custom type schema
+// without a raw schema: the CustomTypeByte type byte followed by a UUID
+// consisting of schemaRefPrefix and the registry ID of the schema as 8 bytes in
+// little-endian byte order.
+var schemaRefPrefix = [8]byte{0x3b, 0x5e, 0x91, 0x0c, 0x47, 0xd2, 0x4e, 0x86}
+
+// maxInlineSchemaLen is the length of a schema reference
+const maxInlineSchemaLen = 1 + len(UUID{})
+
+// schemaRefID returns the registry ID of a schema reference with the
+// specified UUID, or false if id is not the UUID of a schema reference
+func schemaRefID(id UUID) (uint64, bool) {
+	if !bytes.Equal(id[:8], schemaRefPrefix[:]) {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint64(id[8:]), true
+}
+
+// appendSchemaRef appends the binary schema of a variant or schema value to
+// dst. If m is a Schema larger than a schema reference and reg has a
+// SchemaRegistry, m is registered and a schema reference is appended instead.
+func (reg *Registry) appendSchemaRef(dst []byte, m Marshaler) ([]byte, error) {
+	b, err := m.MarshalSchemer()
+	if err != nil {
+		return dst, err
+	}
+	sr := reg.SchemaRegistry()
+	s, ok := m.(Schema)
+	if sr == nil || !ok || len(b) <= maxInlineSchemaLen {
+		return append(dst, b...), nil
+	}
+
+	id, err := sr.Register(s)
+	if err != nil {
+		return dst, fmt.Errorf("schema reference: %w", err)
+	}
+	var ref UUID
+	copy(ref[:], schemaRefPrefix[:])
+	binary.LittleEndian.PutUint64(ref[8:], id)
+	dst = append(dst, CustomTypeByte)
+	return append(dst, ref[:]...), nil
+}
+
+// lookupSchemaRef returns the schema of a schema reference with the specified
+// registry ID
+func (reg *Registry) lookupSchemaRef(id uint64, nullable bool) (Schema, error) {
+	if nullable {
+		return nil, fmt.Errorf("schema reference %d cannot be nullable", id)
+	}
+	sr := reg.SchemaRegistry()
+	if sr == nil {
+		return nil, fmt.Errorf("schema reference %d: no SchemaRegistry is set", id)
+	}
+	s, err := sr.Lookup(id)
+	if err != nil {
+		return nil, fmt.Errorf("schema reference: %w", err)
+	}
+	return s, nil
+}
+
+// schemaEntry is a schema stored in a schemaIndex
+type schemaEntry struct {
+	id          uint64
+	fingerprint uint64
+	canonical   []byte
+	schema      Schema
+}
+
+// schemaIndex indexes registered schemas by ID and fingerprint. IDs are
+// assigned sequentially starting at 1. A schemaIndex is not safe for
+// concurrent use.
+type schemaIndex struct {
+	byID          map[uint64]*schemaEntry
+	byFingerprint map[uint64]*schemaEntry
+	lastID        uint64
+}
+
+// newSchemaEntry returns a schemaEntry for s with a zero ID
+func newSchemaEntry(s Schema) (*schemaEntry, error) {
+	canonical, err := CanonicalForm(s)
+	if err != nil {
+		return nil, err
+	}
+	fp, err := Fingerprint64(s)
+	if err != nil {
+		return nil, err
+	}
+	return &schemaEntry{
+		fingerprint: fp,
+		canonical:   canonical,
+		schema:      CloneSchema(s),
+	}, nil
+}
+
+// find returns the entry equivalent to e, or nil if there is no such entry.
+// An error is returned if another schema has the same fingerprint.
+func (idx *schemaIndex) find(e *schemaEntry) (*schemaEntry, error) {
+	found := idx.byFingerprint[e.fingerprint]
+	if found == nil {
+		return nil, nil
+	}
+	if !bytes.Equal(found.canonical, e.canonical) {
+		return nil, fmt.Errorf("schema fingerprint %016x conflicts with schema %d",
+			e.fingerprint, found.id)
+	}
+	return found, nil
+}
+
+// add adds e to the index. If e.id is 0, the next unused ID is assigned.
+func (idx *schemaIndex) add(e *schemaEntry) {
+	if idx.byID == nil {
+		idx.byID = make(map[uint64]*schemaEntry)
+		idx.byFingerprint = make(map[uint64]*schemaEntry)
+	}
+	if e.id == 0 {
+		e.id = idx.lastID + 1
+	}
+	if e.id > idx.lastID {
+		idx.lastID = e.id
+	}
+	idx.byID[e.id] = e
+	if _, ok := idx.byFingerprint[e.fingerprint]; !ok {
+		idx.byFingerprint[e.fingerprint] = e
+	}
+}
+
+// MemorySchemaRegistry is a SchemaRegistry that stores schemas in memory.
+// The zero value is an empty MemorySchemaRegistry ready to use.
+type MemorySchemaRegistry struct {
+	mu  sync.RWMutex
+	idx schemaIndex
+}
+
+// NewMemorySchemaRegistry returns a new, empty MemorySchemaRegistry
+func NewMemorySchemaRegistry() *MemorySchemaRegistry {
+	return &MemorySchemaRegistry{}
+}
+
+// Register adds s to the registry and returns its ID. If an equivalent schema
+// is already registered, its ID is returned.
+func (reg *MemorySchemaRegistry) Register(s Schema) (uint64, error) {
+	e, err := newSchemaEntry(s)
+	if err != nil {
+		return 0, err
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	found, err := reg.idx.find(e)
+	if err != nil {
+		return 0, err
+	}
+	if found != nil {
+		return found.id, nil
+	}
+	reg.idx.add(e)
+	return e.id, nil
+}
+
+// Lookup returns a copy of the schema with the specified ID
+func (reg *MemorySchemaRegistry) Lookup(id uint64) (Schema, error) {
+	reg.mu.RLock()
+	e := reg.idx.byID[id]
+	reg.mu.RUnlock()
+
+	if e == nil {
+		return nil, fmt.Errorf("%w: id %d", ErrSchemaNotFound, id)
+	}
+	return CloneSchema(e.schema), nil
+}
+
+// LookupFingerprint returns the ID and a copy of the schema with the
+// specified fingerprint
+func (reg *MemorySchemaRegistry) LookupFingerprint(fp uint64) (uint64, Schema, error) {
+	reg.mu.RLock()
+	e := reg.idx.byFingerprint[fp]
+	reg.mu.RUnlock()
+
+	if e == nil {
+		return 0, nil, fmt.Errorf("%w: fingerprint %016x", ErrSchemaNotFound, fp)
+	}
+	return e.id, CloneSchema(e.schema), nil
+}
@@ -0,0 +1,171 @@
+package schemer
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testSchemaRegistry tests the behavior common to all SchemaRegistry
+// implementations
+func testSchemaRegistry(t *testing.T, reg SchemaRegistry) {
+	t.Helper()
+
+	s1, err := SchemaOf(testRecord{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2 := &VarArraySchema{Element: &VarStringSchema{}}
+
+	id1, err := reg.Register(s1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id2, err := reg.Register(s2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id1 == 0 || id2 == 0 || id1 == id2 {
+		t.Fatalf("unexpected IDs %d and %d", id1, id2)
+	}
+
+	// equivalent schemas have the same ID
+	again, err := reg.Register(CloneSchema(s1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != id1 {
+		t.Errorf("expected ID %d; got %d", id1, again)
+	}
+
+	s, err := reg.Lookup(id1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.GoType() != s1.GoType() {
+		t.Errorf("unexpected schema %v", s)
+	}
+
+	fp, err := Fingerprint64(s2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, s, err := reg.LookupFingerprint(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != id2 || s.GoType() != s2.GoType() {
+		t.Errorf("unexpected schema %d %v", id, s)
+	}
+
+	if _, err = reg.Lookup(id2 + 100); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound; got %v", err)
+	}
+	if _, _, err = reg.LookupFingerprint(fp + 1); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound; got %v", err)
+	}
+}
+
+func TestMemorySchemaRegistry(t *testing.T) {
+	testSchemaRegistry(t, NewMemorySchemaRegistry())
+}
+
+func TestDirSchemaRegistry(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "schemas")
+	reg, err := NewDirSchemaRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSchemaRegistry(t, reg)
+
+	// schemas are shared with other registries using the same directory
+	other, err := NewDirSchemaRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := other.Register(&BoolSchema{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 3 {
+		t.Errorf("expected ID 3; got %d", id)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "3.json")); err != nil {
+		t.Error(err)
+	}
+	if _, err = reg.Lookup(id); err != nil {
+		t.Error(err)
+	}
+	if id, err = reg.Register(&BoolSchema{}); err != nil || id != 3 {
+		t.Errorf("expected ID 3; got %d, %v", id, err)
+	}
+
+	// the directory is not read when registering indexed schemas
+	err = os.WriteFile(filepath.Join(dir, "99.json"), []byte{0xFF}, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err = reg.Register(&BoolSchema{}); err != nil || id != 3 {
+		t.Errorf("expected ID 3; got %d, %v", id, err)
+	}
+
+	// files that cannot be decoded are skipped, but their IDs are not reused
+	if id, err = reg.Register(&VarStringSchema{}); err != nil || id != 100 {
+		t.Errorf("expected ID 100; got %d, %v", id, err)
+	}
+	if _, err = reg.Lookup(99); err == nil || errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected error decoding schema file; got %v", err)
+	}
+
+	// default values are stored with the schema
+	obj := &FixedObjectSchema{Fields: []ObjectField{
+		{Aliases: []string{"A"}, Schema: &VarIntSchema{Signed: true}, Default: 5},
+	}}
+	if id, err = reg.Register(obj); err != nil {
+		t.Fatal(err)
+	}
+	s, err := other.Lookup(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def := s.(*FixedObjectSchema).Fields[0].Default; def == nil {
+		t.Error("expected default value to be stored")
+	}
+}
+
+func TestSchemaRegistryHTTP(t *testing.T) {
+	backend := NewMemorySchemaRegistry()
+	mux := http.NewServeMux()
+	mux.Handle("/registry/", http.StripPrefix("/registry", NewSchemaRegistryHandler(backend)))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewSchemaRegistryClient(server.URL + "/registry")
+	client.Client = server.Client()
+	testSchemaRegistry(t, client)
+
+	// schemas registered by other clients are fetched from the server
+	id, err := backend.Register(&BoolSchema{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := client.Lookup(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(*BoolSchema); !ok {
+		t.Errorf("unexpected schema %v", s)
+	}
+
+	resp, err := server.Client().Get(server.URL + "/registry/schemas/abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected status %s", resp.Status)
+	}
+}
@@ -0,0 +1,255 @@
+package schemer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// schemaFileExt is the file extension of schemas stored by DirSchemaRegistry
+const schemaFileExt = ".json"
+
+// DirSchemaRegistry is a SchemaRegistry that stores each schema in its own
+// file within a directory. Files are named by schema ID (i.e. "12.json") and
+// contain the schema's JSON encoding, which includes the default values of
+// object fields. Several processes may share the same directory; schemas
+// registered by other processes are loaded as needed.
+//
+// Unlike MemorySchemaRegistry, which keeps the registered Schema values,
+// DirSchemaRegistry returns schemas decoded from their files using
+// DefaultRegistry. Weak decoding settings are not part of the JSON encoding,
+// so they are not restored, and custom types must be registered with
+// DefaultRegistry. Files that cannot be decoded are skipped; looking up their
+// IDs returns the error.
+type DirSchemaRegistry struct {
+	dir string
+
+	mu  sync.Mutex
+	idx schemaIndex
+	bad map[uint64]error // files that cannot be decoded, by ID
+}
+
+// NewDirSchemaRegistry returns a DirSchemaRegistry that stores schemas in the
+// specified directory. The directory is created if it does not exist, and
+// schemas already stored in it are loaded.
+func NewDirSchemaRegistry(dir string) (*DirSchemaRegistry, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	reg := &DirSchemaRegistry{dir: dir}
+	err = reg.load()
+	if err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// Dir returns the directory in which schemas are stored
+func (reg *DirSchemaRegistry) Dir() string {
+	return reg.dir
+}
+
+// path returns the name of the file for the specified schema ID
+func (reg *DirSchemaRegistry) path(id uint64) string {
+	return filepath.Join(reg.dir, strconv.FormatUint(id, 10)+schemaFileExt)
+}
+
+// load adds schemas stored in the directory that are not yet in the index.
+// reg.mu must be held.
+func (reg *DirSchemaRegistry) load() error {
+	files, err := os.ReadDir(reg.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, schemaFileExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, schemaFileExt), 10, 64)
+		if err != nil || id == 0 {
+			continue
+		}
+		if _, ok := reg.idx.byID[id]; ok {
+			continue
+		}
+		if _, ok := reg.bad[id]; ok {
+			continue
+		}
+		err = reg.loadFile(id)
+		if err != nil && !reg.isBad(id) {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadFile reads the schema with the specified ID and adds it to the index.
+// If the file cannot be decoded, the error is recorded (see isBad) and
+// returned. reg.mu must be held.
+func (reg *DirSchemaRegistry) loadFile(id uint64) error {
+	b, err := os.ReadFile(reg.path(id))
+	if err != nil {
+		return err
+	}
+
+	s, err := DecodeSchemaJSON(bytes.NewReader(b))
+	var e *schemaEntry
+	if err == nil {
+		e, err = newSchemaEntry(s)
+	}
+	if err != nil {
+		err = fmt.Errorf("schema file %d%s: %w", id, schemaFileExt, err)
+		if reg.bad == nil {
+			reg.bad = make(map[uint64]error)
+		}
+		reg.bad[id] = err
+		// the ID is in use, even though the schema cannot be decoded
+		if id > reg.idx.lastID {
+			reg.idx.lastID = id
+		}
+		return err
+	}
+	e.id = id
+	reg.idx.add(e)
+	return nil
+}
+
+// isBad returns true if the file with the specified ID cannot be decoded.
+// reg.mu must be held.
+func (reg *DirSchemaRegistry) isBad(id uint64) bool {
+	_, ok := reg.bad[id]
+	return ok
+}
+
+// Register adds s to the registry and returns its ID. If an equivalent schema
+// is already stored in the directory, its ID is returned.
+func (reg *DirSchemaRegistry) Register(s Schema) (uint64, error) {
+	e, err := newSchemaEntry(s)
+	if err != nil {
+		return 0, err
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return 0, err
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	// The directory is only read if the schema is not already indexed
+	found, err := reg.idx.find(e)
+	if err != nil {
+		return 0, err
+	}
+	if found != nil {
+		return found.id, nil
+	}
+
+	for {
+		// Another process may have registered the schema
+		err = reg.load()
+		if err != nil {
+			return 0, err
+		}
+		found, err = reg.idx.find(e)
+		if err != nil {
+			return 0, err
+		}
+		if found != nil {
+			return found.id, nil
+		}
+
+		id := reg.idx.lastID + 1
+		created, err := reg.create(id, b)
+		if err != nil {
+			return 0, err
+		}
+		if created {
+			e.id = id
+			reg.idx.add(e)
+			return id, nil
+		}
+		// Another process used this ID first; try again
+	}
+}
+
+// create atomically writes the schema file for the specified ID. false is
+// returned if the file already exists.
+func (reg *DirSchemaRegistry) create(id uint64, b []byte) (bool, error) {
+	tmp, err := os.CreateTemp(reg.dir, ".tmp-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(b)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Unlike os.Rename, os.Link fails if the destination exists
+	err = os.Link(tmp.Name(), reg.path(id))
+	if os.IsExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Lookup returns the schema with the specified ID
+func (reg *DirSchemaRegistry) Lookup(id uint64) (Schema, error) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	e := reg.idx.byID[id]
+	if err, ok := reg.bad[id]; ok {
+		return nil, err
+	}
+	if e == nil && id != 0 {
+		err := reg.loadFile(id)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: id %d", ErrSchemaNotFound, id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		e = reg.idx.byID[id]
+	}
+	if e == nil {
+		return nil, fmt.Errorf("%w: id %d", ErrSchemaNotFound, id)
+	}
+	return CloneSchema(e.schema), nil
+}
+
+// LookupFingerprint returns the ID and schema with the specified fingerprint
+func (reg *DirSchemaRegistry) LookupFingerprint(fp uint64) (uint64, Schema, error) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	e := reg.idx.byFingerprint[fp]
+	if e == nil {
+		err := reg.load()
+		if err != nil {
+			return 0, nil, err
+		}
+		e = reg.idx.byFingerprint[fp]
+	}
+	if e == nil {
+		return 0, nil, fmt.Errorf("%w: fingerprint %016x", ErrSchemaNotFound, fp)
+	}
+	return e.id, CloneSchema(e.schema), nil
+}
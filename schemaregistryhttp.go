@@ -0,0 +1,277 @@
+package schemer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// schemaIDHeader is the HTTP header used to return the ID of a schema
+const schemaIDHeader = "Schemer-Schema-Id"
+
+// maxSchemaSize limits the size of schemas read by the HTTP handler and client
+const maxSchemaSize = 1 << 20
+
+// NewSchemaRegistryHandler returns an http.Handler that serves the schemas of
+// reg. Schemas are transferred in their portable binary format (see
+// MarshalSchemer). The handler serves the following requests:
+//   - POST /schemas registers the schema in the request body and responds
+//     with a JSON object containing its ID (i.e. {"id":12})
+//   - GET /schemas/{id} responds with the schema with the specified ID
+//   - GET /fingerprints/{fp} responds with the schema whose 64-bit
+//     fingerprint is fp (16 hexadecimal digits); the schema's ID is returned
+//     in the Schemer-Schema-Id header
+//
+// Use http.StripPrefix to serve the registry below a path prefix.
+// SchemaRegistryClient is a client for this handler.
+func NewSchemaRegistryHandler(reg SchemaRegistry) http.Handler {
+	return &schemaRegistryHandler{reg: reg}
+}
+
+type schemaRegistryHandler struct {
+	reg SchemaRegistry
+}
+
+func (h *schemaRegistryHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := strings.TrimPrefix(req.URL.Path, "/")
+	switch {
+	case path == "schemas":
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.register(w, req)
+
+	case strings.HasPrefix(path, "schemas/"):
+		id, err := strconv.ParseUint(strings.TrimPrefix(path, "schemas/"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid schema ID", http.StatusBadRequest)
+			return
+		}
+		if !h.allowGet(w, req) {
+			return
+		}
+		s, err := h.reg.Lookup(id)
+		h.writeSchema(w, id, s, err)
+
+	case strings.HasPrefix(path, "fingerprints/"):
+		fp, err := strconv.ParseUint(strings.TrimPrefix(path, "fingerprints/"), 16, 64)
+		if err != nil {
+			http.Error(w, "invalid schema fingerprint", http.StatusBadRequest)
+			return
+		}
+		if !h.allowGet(w, req) {
+			return
+		}
+		id, s, err := h.reg.LookupFingerprint(fp)
+		h.writeSchema(w, id, s, err)
+
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (h *schemaRegistryHandler) allowGet(w http.ResponseWriter, req *http.Request) bool {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+func (h *schemaRegistryHandler) register(w http.ResponseWriter, req *http.Request) {
+	s, err := DecodeSchema(io.LimitReader(req.Body, maxSchemaSize))
+	if err != nil {
+		http.Error(w, "invalid schema: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := h.reg.Register(s)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]uint64{"id": id})
+}
+
+func (h *schemaRegistryHandler) writeSchema(w http.ResponseWriter, id uint64, s Schema, err error) {
+	if errors.Is(err, ErrSchemaNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	m, ok := s.(Marshaler)
+	if !ok {
+		http.Error(w, "schema does not implement MarshalSchemer", http.StatusInternalServerError)
+		return
+	}
+	b, err := m.MarshalSchemer()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set(schemaIDHeader, strconv.FormatUint(id, 10))
+	w.Write(b)
+}
+
+// SchemaRegistryClient is a SchemaRegistry that uses a remote registry served
+// by NewSchemaRegistryHandler. Schemas are immutable once registered, so
+// schemas that have been looked up or registered are cached by the client.
+type SchemaRegistryClient struct {
+	// URL is the base URL of the registry (i.e. "http://localhost:8080/registry")
+	URL string
+
+	// Client is the HTTP client used for requests. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	cache MemorySchemaRegistry
+}
+
+// NewSchemaRegistryClient returns a SchemaRegistryClient for the registry with
+// the specified base URL
+func NewSchemaRegistryClient(baseURL string) *SchemaRegistryClient {
+	return &SchemaRegistryClient{URL: baseURL}
+}
+
+func (c *SchemaRegistryClient) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return http.DefaultClient
+}
+
+// endpoint returns the URL of the specified path relative to c.URL
+func (c *SchemaRegistryClient) endpoint(path string) string {
+	return strings.TrimSuffix(c.URL, "/") + "/" + path
+}
+
+// Register registers s with the remote registry and returns its ID
+func (c *SchemaRegistryClient) Register(s Schema) (uint64, error) {
+	fp, err := Fingerprint64(s)
+	if err != nil {
+		return 0, err
+	}
+	if id, _, err := c.cache.LookupFingerprint(fp); err == nil {
+		return id, nil
+	}
+
+	m, ok := s.(Marshaler)
+	if !ok {
+		return 0, fmt.Errorf("schema does not implement MarshalSchemer")
+	}
+	b, err := m.MarshalSchemer()
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := c.client().Post(c.endpoint("schemas"), "application/octet-stream",
+		bytes.NewReader(b))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if err = checkResponse(resp); err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		ID uint64 `json:"id"`
+	}
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxSchemaSize)).Decode(&result)
+	if err != nil {
+		return 0, fmt.Errorf("schema registry: invalid response: %w", err)
+	}
+
+	c.store(result.ID, s)
+	return result.ID, nil
+}
+
+// Lookup returns the schema with the specified ID
+func (c *SchemaRegistryClient) Lookup(id uint64) (Schema, error) {
+	if s, err := c.cache.Lookup(id); err == nil {
+		return s, nil
+	}
+	_, s, err := c.get("schemas/" + strconv.FormatUint(id, 10))
+	if err != nil {
+		return nil, err
+	}
+	c.store(id, s)
+	return s, nil
+}
+
+// LookupFingerprint returns the ID and schema with the specified fingerprint
+func (c *SchemaRegistryClient) LookupFingerprint(fp uint64) (uint64, Schema, error) {
+	if id, s, err := c.cache.LookupFingerprint(fp); err == nil {
+		return id, s, nil
+	}
+	id, s, err := c.get("fingerprints/" + fmt.Sprintf("%016x", fp))
+	if err != nil {
+		return 0, nil, err
+	}
+	c.store(id, s)
+	return id, s, nil
+}
+
+// get requests a schema from the remote registry
+func (c *SchemaRegistryClient) get(path string) (uint64, Schema, error) {
+	resp, err := c.client().Get(c.endpoint(path))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	if err = checkResponse(resp); err != nil {
+		return 0, nil, err
+	}
+
+	id, err := strconv.ParseUint(resp.Header.Get(schemaIDHeader), 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("schema registry: invalid %s header", schemaIDHeader)
+	}
+	s, err := DecodeSchema(io.LimitReader(resp.Body, maxSchemaSize))
+	if err != nil {
+		return 0, nil, fmt.Errorf("schema registry: invalid schema: %w", err)
+	}
+	return id, s, nil
+}
+
+// store adds a schema returned by the remote registry to the cache
+func (c *SchemaRegistryClient) store(id uint64, s Schema) {
+	e, err := newSchemaEntry(s)
+	if err != nil {
+		return
+	}
+	e.id = id
+
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+	if _, ok := c.cache.idx.byID[id]; !ok {
+		c.cache.idx.add(e)
+	}
+}
+
+// checkResponse returns an error if resp is not successful. ErrSchemaNotFound
+// is returned (wrapped) if the schema was not found.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrSchemaNotFound, resp.Request.URL.Path)
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("schema registry: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
@@ -0,0 +1,161 @@
+package schemer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// schemaType is the reflect.Type of the Schema interface
+var schemaType = reflect.TypeOf((*Schema)(nil)).Elem()
+
+// SchemaSchema is a Schema for encoding and decoding schemer schemas as values.
+// Schemas are encoded using their portable binary format (see MarshalSchemer)
+// and are decoded using the DecodeSchema method of the Registry that created
+// the SchemaSchema, or DefaultRegistry if it was not created by a Registry.
+// Large schemas are written as a reference if the Registry has a
+// SchemaRegistry (see Registry.SetSchemaRegistry).
+type SchemaSchema struct {
+	SchemaOptions
+
+	reg *Registry // nil means DefaultRegistry
+}
+
+// registry returns the Registry used to decode schemas
+func (s *SchemaSchema) registry() *Registry {
+	if s.reg == nil {
+		return DefaultRegistry
+	}
+	return s.reg
+}
+
+func (s *SchemaSchema) GoType() reflect.Type {
+	// Note: Schema is an interface type, which can hold nil values
+	return schemaType
+}
+
+func (s *SchemaSchema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"type":     "schema",
+		"nullable": s.Nullable(),
+	})
+}
+
+// Bytes encodes the schema in a portable binary format
+func (s *SchemaSchema) MarshalSchemer() ([]byte, error) {
+
+	// schema schemas are 1 byte long
+	var schema []byte = []byte{SchemaByte}
+
+	// The most signifiant bit indicates whether or not the type is nullable
+	if s.Nullable() {
+		schema[0] |= NullMask
+	}
+
+	return schema, nil
+}
+
+// Encode uses the schema to write the encoded value of i to the output stream
+func (s *SchemaSchema) Encode(w io.Writer, i interface{}) error {
+	return s.EncodeValue(w, reflect.ValueOf(i))
+}
+
+// EncodeValue uses the schema to write the encoded value of v to the output
+// stream. v must implement Marshaler.
+func (s *SchemaSchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
+
+// AppendEncode appends the encoded value of i to dst and returns the extended
+// slice. i must implement Marshaler.
+func (s *SchemaSchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
+
+// AppendEncodeValue appends the encoded value of v to dst and returns the
+// extended slice. v must implement Marshaler.
+func (s *SchemaSchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
+
+	// Note: PreAppend is not used here because most schemas are pointer types
+	// that must not be dereferenced before calling MarshalSchemer
+	var m Marshaler
+	isNil := false
+	for m == nil {
+		k := v.Kind()
+		if !v.IsValid() || ((k == reflect.Ptr || k == reflect.Interface) && v.IsNil()) {
+			isNil = true
+			break
+		}
+		if tmp, ok := v.Interface().(Marshaler); ok {
+			m = tmp
+		} else if k == reflect.Ptr || k == reflect.Interface {
+			v = v.Elem()
+		} else {
+			return dst, fmt.Errorf("SchemaSchema only supports encoding schemer schemas")
+		}
+	}
+
+	if s.Nullable() {
+		if isNil {
+			// 1 indicates null
+			return append(dst, 1), nil
+		}
+		// 0 indicates not null
+		dst = append(dst, 0)
+	} else if isNil {
+		return dst, fmt.Errorf("cannot encode nil value: schema is not nullable")
+	}
+
+	return s.registry().appendSchemaRef(dst, m)
+}
+
+// Decode uses the schema to read the next encoded value from the input stream and store it in i
+func (s *SchemaSchema) Decode(r io.Reader, i interface{}) error {
+	if i == nil {
+		return fmt.Errorf("cannot decode to nil destination")
+	}
+	return s.DecodeValue(r, reflect.ValueOf(i))
+}
+
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *SchemaSchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
+// DecodeValue uses the schema to read the next encoded value from the input
+// stream and store it in v. v is usually a Schema or an empty interface.
+func (s *SchemaSchema) DecodeValue(r io.Reader, v reflect.Value) error {
+
+	v = resetInterface(v)
+
+	done, err := PreDecode(r, &v, s.Nullable())
+	if err != nil || done {
+		return err
+	}
+
+	decoded, err := s.registry().DecodeSchema(r)
+	if err != nil {
+		return err
+	}
+
+	// Ensure v is settable
+	if !v.CanSet() {
+		return fmt.Errorf("decode destination is not settable")
+	}
+
+	sv := reflect.ValueOf(decoded)
+	if sv.Type().AssignableTo(v.Type()) {
+		v.Set(sv)
+		return nil
+	}
+
+	// PreDecode may have allocated the schema struct pointed to by v
+	if sv.Kind() == reflect.Ptr && sv.Elem().Type().AssignableTo(v.Type()) {
+		v.Set(sv.Elem())
+		return nil
+	}
+
+	return fmt.Errorf("decoded schema of type %v is not assignable to %v",
+		sv.Type(), v.Type())
+}
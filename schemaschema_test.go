@@ -0,0 +1,168 @@
+package schemer
+
+import (
+	"bytes"
+	"testing"
+)
+
+type schemaEnvelope struct {
+	Name       string
+	Descriptor Schema
+}
+
+func TestSchemaSchemaOf(t *testing.T) {
+
+	s, err := SchemaOf(schemaEnvelope{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixedObjectSchema, ok := s.(*FixedObjectSchema)
+	if !ok {
+		t.Fatal("fixedObjectSchema assertion failed")
+	}
+
+	schemaSchema, ok := fixedObjectSchema.Fields[1].Schema.(*SchemaSchema)
+	if !ok {
+		t.Fatal("expected SchemaSchema for Schema field")
+	}
+	if !schemaSchema.Nullable() {
+		t.Error("expected Schema field to be nullable")
+	}
+
+	// JSON round trip
+	b, err := schemaSchema.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp, err := DecodeSchemaJSON(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, ok := tmp.(*SchemaSchema); !ok || !decoded.Nullable() {
+		t.Fatal("unexpected values when decoding JSON SchemaSchema")
+	}
+
+	// binary round trip
+	b, err = schemaSchema.MarshalSchemer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp, err = DecodeSchema(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, ok := tmp.(*SchemaSchema); !ok || !decoded.Nullable() {
+		t.Fatal("unexpected values when decoding binary SchemaSchema")
+	}
+}
+
+func TestDecodeSchemaSchema1(t *testing.T) {
+
+	envelopeSchema, err := SchemaOf(schemaEnvelope{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	descriptor, err := SchemaOf(sourceStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = envelopeSchema.Encode(&buf, schemaEnvelope{
+		Name:       "sourceStruct",
+		Descriptor: descriptor,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded schemaEnvelope
+	err = envelopeSchema.Decode(bytes.NewReader(buf.Bytes()), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Name != "sourceStruct" {
+		t.Error("unexpected name decode")
+	}
+
+	expected, err := descriptor.(Marshaler).MarshalSchemer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := decoded.Descriptor.(Marshaler).MarshalSchemer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Error("decoded schema does not match encoded schema")
+	}
+
+	// nil schemas are allowed, since Schema fields are nullable
+	buf.Reset()
+	err = envelopeSchema.Encode(&buf, schemaEnvelope{Name: "nil"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded.Descriptor = descriptor
+	err = envelopeSchema.Decode(bytes.NewReader(buf.Bytes()), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Descriptor != nil {
+		t.Error("expected nil schema")
+	}
+}
+
+// decode to empty interface and concrete schema types
+func TestDecodeSchemaSchema2(t *testing.T) {
+
+	s := &SchemaSchema{}
+
+	var buf bytes.Buffer
+	err := s.Encode(&buf, &VarIntSchema{Signed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var i interface{}
+	err = s.Decode(bytes.NewReader(buf.Bytes()), &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if varIntSchema, ok := i.(*VarIntSchema); !ok || !varIntSchema.Signed {
+		t.Errorf("unexpected schema decode: %#v", i)
+	}
+
+	var varIntSchema *VarIntSchema
+	err = s.Decode(bytes.NewReader(buf.Bytes()), &varIntSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if varIntSchema == nil || !varIntSchema.Signed {
+		t.Errorf("unexpected schema decode: %#v", varIntSchema)
+	}
+
+	var floatSchema *FloatSchema
+	err = s.Decode(bytes.NewReader(buf.Bytes()), &floatSchema)
+	if err == nil {
+		t.Error("expected error decoding VarIntSchema to *FloatSchema")
+	}
+}
+
+// corner case:
+// make sure non-schema values and nil values are refused
+func TestEncodeSchemaSchema(t *testing.T) {
+
+	s := &SchemaSchema{}
+
+	var buf bytes.Buffer
+	if err := s.Encode(&buf, 42); err == nil {
+		t.Error("expected error encoding int with SchemaSchema")
+	}
+	if err := s.Encode(&buf, nil); err == nil {
+		t.Error("expected error encoding nil with non-nullable SchemaSchema")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
@@ -11,12 +12,6 @@ import (
 	"strings"
 )
 
-// initialization function for the Schemer Library
-func init() {
-	Register(dateSchemaGenerator{})
-	Register(ipv4SchemaGenerator{})
-}
-
 // Schema is an interface that encodes and decodes data of a specific type
 type Schema interface {
 	// Encode uses the schema to write the encoded value of i to the output
@@ -45,15 +40,31 @@ type Marshaler interface {
 	MarshalSchemer() ([]byte, error)
 }
 
+// Appender is an interface implemented by schemas that can append encoded
+// values to a byte slice rather than writing them to an io.Writer, so that a
+// single buffer can be reused to encode many values. All built-in schemas
+// implement Appender.
+type Appender interface {
+	// AppendEncode appends the encoded value of i to dst and returns the
+	// extended slice
+	AppendEncode(dst []byte, i interface{}) ([]byte, error)
+
+	// AppendEncodeValue appends the encoded value of v to dst and returns the
+	// extended slice
+	AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error)
+}
+
 // SchemaGenerator is an interface implemented by custom schema generators.
-// When Register is called on a SchemaGenerator, the global SchemaOf,
-// DecodeSchema, and DecodeSchemaJSON functions will call the identically
-// named method on each schema generator to determine if a custom schema should
-// be returned.
+// When a SchemaGenerator is registered with a Registry, the SchemaOf,
+// DecodeSchema, and DecodeSchemaJSON methods of the Registry will call the
+// identically named method on each schema generator (in order of priority) to
+// determine if a custom schema should be returned.
 // If a SchemaGenerator cannot return a Schema for a specific type, it should
 // return nil, nil.
 // If all schema generators return a nil Schema or if Register is never called,
 // then the built-in logic for returning a Schema is used.
+// Generators of custom types should also implement CustomTypeGenerator to
+// declare the UUID of the custom type.
 type SchemaGenerator interface {
 	SchemaOfType(t reflect.Type) (Schema, error)
 	DecodeSchema(r io.Reader) (Schema, error)
@@ -70,36 +81,11 @@ type hasDecodeSchemaJSON interface {
 	DecodeSchemaJSON(r io.Reader) (Schema, error)
 }
 
-var (
-	regSchemaOfType     = []hasSchemaOfType{}
-	regDecodeSchema     = []hasDecodeSchema{}
-	regDecodeSchemaJSON = []hasDecodeSchemaJSON{}
-)
-
-// Register records custom schema generators that implement `SchemaOfType`,
-// `DecodeSchema`, and/or `DecodeSchemaJSON`. When `schemer.SchemaOfType` is
-// called, `SchemaOfType` is called on each registered schema generator to
-// determine if a custom Schema should be used for a given type.
-func Register(ifaces ...interface{}) error {
-	for _, iface := range ifaces {
-		if sg, ok := iface.(hasSchemaOfType); ok {
-			regSchemaOfType = append(regSchemaOfType, sg)
-		}
-		if sg, ok := iface.(hasDecodeSchema); ok {
-			regDecodeSchema = append(regDecodeSchema, sg)
-		}
-		if sg, ok := iface.(hasDecodeSchemaJSON); ok {
-			regDecodeSchemaJSON = append(regDecodeSchemaJSON, sg)
-		}
-	}
-	return nil
-}
-
 // SchemaOf returns a Schema for the specified interface value.
 // If i is a pointer or interface type, the value of the pointer/interface is
 // used to generate the Schema.
 // If i is nil, an zero-field FixedObjectSchema is returned.
-func SchemaOf(i interface{}) (Schema, error) {
+func (reg *Registry) SchemaOf(i interface{}) (Schema, error) {
 	if i == nil {
 		// Return a Schema for an empty struct
 		return &FixedObjectSchema{}, nil
@@ -112,13 +98,14 @@ func SchemaOf(i interface{}) (Schema, error) {
 		t = t.Elem()
 	}
 
-	return SchemaOfType(t)
+	return reg.SchemaOfType(t)
 }
 
-// SchemaOfType returns a Schema for the specified Go type
-func SchemaOfType(t reflect.Type) (Schema, error) {
+// schemaOfType builds a Schema for the specified Go type without consulting
+// the schema cache (see Registry.SchemaOfType)
+func (reg *Registry) schemaOfType(gens *registryGenerators, t reflect.Type) (Schema, error) {
 	// Call registered schema generators
-	for _, sg := range regSchemaOfType {
+	for _, sg := range gens.schemaOfType {
 		if s, err := sg.SchemaOfType(t); s != nil || err != nil {
 			return s, err
 		}
@@ -126,10 +113,8 @@ func SchemaOfType(t reflect.Type) (Schema, error) {
 
 	nullable := false
 
-	// Dereference pointer / interface types
-	for k := t.Kind(); k == reflect.Ptr ||
-		k == reflect.Interface; k = t.Kind() {
-
+	// Dereference pointer types
+	for t.Kind() == reflect.Ptr {
 		t = t.Elem()
 
 		// If we encounter any pointers, then we know this type is nullable
@@ -139,6 +124,18 @@ func SchemaOfType(t reflect.Type) (Schema, error) {
 	k := t.Kind()
 
 	switch k {
+	// the dynamic type of an interface is unknown until a value is encoded,
+	// so interfaces (which can always be nil) are encoded as variants
+	case reflect.Interface:
+		if t == schemaType {
+			s := &SchemaSchema{reg: reg}
+			s.SetNullable(true)
+			return s, nil
+		}
+		s := &VariantSchema{reg: reg}
+		s.SetNullable(true)
+		return s, nil
+
 	case reflect.Bool:
 		s := &BoolSchema{}
 		s.SetNullable(nullable)
@@ -197,7 +194,12 @@ func SchemaOfType(t reflect.Type) (Schema, error) {
 		return s, nil
 
 	case reflect.Array:
-		el, err := SchemaOfType(t.Elem())
+		if t.Elem().Kind() == reflect.Uint8 {
+			s := &FixedBytesSchema{Length: t.Len()}
+			s.SetNullable(nullable)
+			return s, nil
+		}
+		el, err := reg.SchemaOfType(t.Elem())
 		if err != nil {
 			return nil, fmt.Errorf("array type: %w", err)
 		}
@@ -209,7 +211,12 @@ func SchemaOfType(t reflect.Type) (Schema, error) {
 		return s, nil
 
 	case reflect.Slice:
-		el, err := SchemaOfType(t.Elem())
+		if t.Elem().Kind() == reflect.Uint8 {
+			s := &VarBytesSchema{}
+			s.SetNullable(nullable)
+			return s, nil
+		}
+		el, err := reg.SchemaOfType(t.Elem())
 		if err != nil {
 			return nil, fmt.Errorf("slice type: %w", err)
 		}
@@ -220,11 +227,11 @@ func SchemaOfType(t reflect.Type) (Schema, error) {
 		return s, nil
 
 	case reflect.Map:
-		key, err := SchemaOfType(t.Key())
+		key, err := reg.SchemaOfType(t.Key())
 		if err != nil {
 			return nil, fmt.Errorf("map key type: %w", err)
 		}
-		val, err := SchemaOfType(t.Elem())
+		val, err := reg.SchemaOfType(t.Elem())
 		if err != nil {
 			return nil, fmt.Errorf("map value type: %w", err)
 		}
@@ -241,9 +248,10 @@ func SchemaOfType(t reflect.Type) (Schema, error) {
 		}
 		s.SetNullable(nullable)
 
+		fields := structFieldsOf(t)
 		for i := 0; i < t.NumField(); i++ {
 			f := t.Field(i)
-			ofs, err := SchemaOfType(f.Type)
+			ofs, err := reg.SchemaOfType(f.Type)
 			if err != nil {
 				return nil, fmt.Errorf("struct field %v: %w", f.Name, err)
 			}
@@ -256,11 +264,11 @@ func SchemaOfType(t reflect.Type) (Schema, error) {
 				continue // skip this field
 			}
 
-			// Parse struct tag and set aliases and schema options
-			tagOpts := ParseStructTag(f.Tag.Get(StructTagName))
+			// Set aliases and schema options from the struct tag
+			tagOpts := fields[i].tag
 
 			if tagOpts.FieldAliasesSet {
-				of.Aliases = tagOpts.FieldAliases
+				of.Aliases = append([]string(nil), tagOpts.FieldAliases...)
 			} else {
 				// if no aliases set in the tag, use the struct field name
 				of.Aliases = []string{f.Name}
@@ -270,7 +278,11 @@ func SchemaOfType(t reflect.Type) (Schema, error) {
 				continue // skip this field
 			}
 
-			// Note: only override option if explicitly set in the tag
+			// Note: only override option if explicitly set in the tag. The
+			// schema may be shared with the schema cache, so copy it first.
+			if tagOpts.NullableSet || tagOpts.WeakDecodingSet {
+				of.Schema = CloneSchema(of.Schema)
+			}
 			if tagOpts.NullableSet {
 				// Note: Most schemas implement SetNullable(bool), but Schema
 				// does not require it; we must check here
@@ -288,6 +300,17 @@ func SchemaOfType(t reflect.Type) (Schema, error) {
 					opt.SetWeakDecoding(tagOpts.WeakDecoding)
 				}
 			}
+			if tagOpts.DefaultSet {
+				// The default value converted to the field's type
+				if err := fields[i].defErr; err != nil {
+					return nil, fmt.Errorf("struct field %v: %w", f.Name, err)
+				}
+				def := fields[i].def
+				for def.Kind() == reflect.Ptr && !def.IsNil() {
+					def = def.Elem()
+				}
+				of.Default = def.Interface()
+			}
 
 			// Add to FixedObjectSchema field list
 			s.Fields = append(s.Fields, of)
@@ -300,7 +323,7 @@ func SchemaOfType(t reflect.Type) (Schema, error) {
 
 // DecodeSchemaJSON takes a buffer of JSON data and parses it to create a schema
 // The input stream r is read in its entirety before the JSON is decoded.
-func DecodeSchemaJSON(r io.Reader) (Schema, error) {
+func (reg *Registry) DecodeSchemaJSON(r io.Reader) (Schema, error) {
 
 	buf, err := io.ReadAll(r)
 	if err != nil {
@@ -308,7 +331,7 @@ func DecodeSchemaJSON(r io.Reader) (Schema, error) {
 	}
 
 	// Call registered schema generators
-	for _, sg := range regDecodeSchemaJSON {
+	for _, sg := range reg.generators().decodeSchemaJSON {
 		s, err := sg.DecodeSchemaJSON(bytes.NewReader(buf))
 		if s != nil || err != nil {
 			return s, err
@@ -457,6 +480,31 @@ func DecodeSchemaJSON(r io.Reader) (Schema, error) {
 		s.SetNullable(nullable)
 		return s, nil
 
+	case "bytes":
+		lengthI, ok := fields["length"]
+
+		// if length is present, then we are dealing with fixed bytes
+		if ok {
+			lengthNum, ok := lengthI.(float64)
+			if !ok {
+				return nil, fmt.Errorf("length must be a number")
+			}
+
+			// validate that `lengthNum >= 0` and is an integer
+			if (lengthNum < 0) || (lengthNum-float64(int(lengthNum)) != 0) {
+				return nil, fmt.Errorf("invalid bytes length: %v", lengthNum)
+			}
+
+			s := &FixedBytesSchema{Length: int(lengthNum)}
+			s.SetNullable(nullable)
+			return s, nil
+		}
+
+		// variable length bytes
+		s := &VarBytesSchema{}
+		s.SetNullable(nullable)
+		return s, nil
+
 	case "enum":
 		values, ok := fields["values"].(map[string]interface{})
 		if !ok {
@@ -504,7 +552,7 @@ func DecodeSchemaJSON(r io.Reader) (Schema, error) {
 				return nil, err
 			}
 
-			s.Element, err = DecodeSchemaJSON(bytes.NewReader(tmp))
+			s.Element, err = reg.DecodeSchemaJSON(bytes.NewReader(tmp))
 			if err != nil {
 				return nil, err
 			}
@@ -522,7 +570,7 @@ func DecodeSchemaJSON(r io.Reader) (Schema, error) {
 			return nil, err
 		}
 
-		s.Element, err = DecodeSchemaJSON(bytes.NewReader(tmp))
+		s.Element, err = reg.DecodeSchemaJSON(bytes.NewReader(tmp))
 		if err != nil {
 			return nil, err
 		}
@@ -566,13 +614,16 @@ func DecodeSchemaJSON(r io.Reader) (Schema, error) {
 					of.Aliases = append(of.Aliases, nameStr)
 				}
 
+				// Note: the default value is not converted until it is used
+				of.Default = tmpMap["default"]
+
 				// Decode schema for this field
 				tmp, err := json.Marshal(fieldI)
 				if err != nil {
 					return nil, err
 				}
 
-				of.Schema, err = DecodeSchemaJSON(bytes.NewReader(tmp))
+				of.Schema, err = reg.DecodeSchemaJSON(bytes.NewReader(tmp))
 				if err != nil {
 					return nil, err
 				}
@@ -592,7 +643,7 @@ func DecodeSchemaJSON(r io.Reader) (Schema, error) {
 		if err != nil {
 			return nil, err
 		}
-		s.Key, err = DecodeSchemaJSON(bytes.NewReader(tmp))
+		s.Key, err = reg.DecodeSchemaJSON(bytes.NewReader(tmp))
 		if err != nil {
 			return nil, err
 		}
@@ -602,12 +653,22 @@ func DecodeSchemaJSON(r io.Reader) (Schema, error) {
 		if err != nil {
 			return nil, err
 		}
-		s.Value, err = DecodeSchemaJSON(bytes.NewReader(tmp))
+		s.Value, err = reg.DecodeSchemaJSON(bytes.NewReader(tmp))
 		if err != nil {
 			return nil, err
 		}
 
 		return s, nil
+
+	case "variant":
+		s := &VariantSchema{reg: reg}
+		s.SetNullable(nullable)
+		return s, nil
+
+	case "schema":
+		s := &SchemaSchema{reg: reg}
+		s.SetNullable(nullable)
+		return s, nil
 	}
 
 	return nil, fmt.Errorf("invalid schema type: %s", typeStr)
@@ -615,7 +676,7 @@ func DecodeSchemaJSON(r io.Reader) (Schema, error) {
 
 // DecodeSchema decodes a binary encoded schema by reading from r
 // No internal buffering is used when reading from r
-func DecodeSchema(r io.Reader) (Schema, error) {
+func (reg *Registry) DecodeSchema(r io.Reader) (Schema, error) {
 
 	// Save whatever registered schema generators read into `buf`
 	buf := &bytes.Buffer{}
@@ -623,7 +684,7 @@ func DecodeSchema(r io.Reader) (Schema, error) {
 	r = teeR
 
 	// Call registered schema generators
-	for _, sg := range regDecodeSchema {
+	for _, sg := range reg.generators().decodeSchema {
 		if s, err := sg.DecodeSchema(r); s != nil || err != nil {
 			return s, err
 		}
@@ -734,6 +795,28 @@ func DecodeSchema(r io.Reader) (Schema, error) {
 		return s, nil
 	}
 
+	// decode fixed len bytes
+	if curByte&StringMask == FixedBytesByte {
+		s := &FixedBytesSchema{}
+		s.SetNullable(curByte&NullMask > 0)
+
+		i64, err := binary.ReadVarint(byter{r})
+		if err != nil {
+			return nil, err
+		}
+		s.Length = int(i64)
+
+		return s, nil
+	}
+
+	// decode var len bytes
+	if curByte&StringMask == VarBytesByte {
+		s := &VarBytesSchema{}
+		s.SetNullable(curByte&NullMask > 0)
+
+		return s, nil
+	}
+
 	// decode fixed array schema
 	if curByte&ArrayMask == FixedArrayByte {
 		s := &FixedArraySchema{}
@@ -745,7 +828,7 @@ func DecodeSchema(r io.Reader) (Schema, error) {
 		}
 		s.Length = int(i64)
 
-		s.Element, err = DecodeSchema(r)
+		s.Element, err = reg.DecodeSchema(r)
 		if err != nil {
 			return nil, err
 		}
@@ -758,7 +841,7 @@ func DecodeSchema(r io.Reader) (Schema, error) {
 		s := &VarArraySchema{}
 		s.SetNullable(curByte&NullMask > 0)
 
-		s.Element, err = DecodeSchema(r)
+		s.Element, err = reg.DecodeSchema(r)
 		if err != nil {
 			return nil, err
 		}
@@ -797,7 +880,7 @@ func DecodeSchema(r io.Reader) (Schema, error) {
 				of.Aliases = append(of.Aliases, alias)
 			}
 
-			of.Schema, err = DecodeSchema(r)
+			of.Schema, err = reg.DecodeSchema(r)
 			if err != nil {
 				return nil, err
 			}
@@ -813,12 +896,12 @@ func DecodeSchema(r io.Reader) (Schema, error) {
 		s := &VarObjectSchema{}
 		s.SetNullable(curByte&NullMask > 0)
 
-		s.Key, err = DecodeSchema(r)
+		s.Key, err = reg.DecodeSchema(r)
 		if err != nil {
 			return nil, err
 		}
 
-		s.Value, err = DecodeSchema(r)
+		s.Value, err = reg.DecodeSchema(r)
 		if err != nil {
 			return nil, err
 		}
@@ -826,6 +909,27 @@ func DecodeSchema(r io.Reader) (Schema, error) {
 		return s, nil
 	}
 
+	// decode custom type schema
+	if curByte&CustomTypeMask == CustomTypeByte {
+		return reg.decodeCustomSchema(r, curByte&NullMask > 0)
+	}
+
+	// decode variant schema
+	if curByte&VariantMask == VariantByte {
+		s := &VariantSchema{reg: reg}
+		s.SetNullable(curByte&NullMask > 0)
+
+		return s, nil
+	}
+
+	// decode schema schema
+	if curByte&SchemaMask == SchemaByte {
+		s := &SchemaSchema{reg: reg}
+		s.SetNullable(curByte&NullMask > 0)
+
+		return s, nil
+	}
+
 	return nil, fmt.Errorf("invalid binary schema encountered")
 }
 
@@ -860,6 +964,63 @@ func PreEncode(w io.Writer, v *reflect.Value, nullable bool) (bool, error) {
 	return false, nil
 }
 
+// PreAppend is the counterpart of PreEncode for AppendEncodeValue routines. It
+// dereferences v if the value is a pointer or interface type and appends the
+// null byte to dst if nullable is set. The extended slice is returned along
+// with the same flags returned by PreEncode.
+func PreAppend(dst []byte, v *reflect.Value, nullable bool) ([]byte, bool, error) {
+	// Dereference pointer / interface types
+	for k := v.Kind(); k == reflect.Ptr || k == reflect.Interface; k = v.Kind() {
+		*v = v.Elem()
+	}
+
+	// Note: v.Elem() returns invalid Value if v is nil
+	isNil := !v.IsValid()
+
+	if nullable {
+		if isNil {
+			// 1 indicates null
+			return append(dst, 1), true, nil
+		}
+		// 0 indicates not null
+		dst = append(dst, 0)
+	} else if isNil {
+		return dst, false, errNotNullable
+	}
+
+	return dst, false, nil
+}
+
+// appendEncodeValue appends the encoded value of v to dst using schema s. If s
+// does not implement Appender, s.EncodeValue is used instead.
+func appendEncodeValue(s Schema, dst []byte, v reflect.Value) ([]byte, error) {
+	if a, ok := s.(Appender); ok {
+		return a.AppendEncodeValue(dst, v)
+	}
+	w := appendWriter{dst}
+	err := s.EncodeValue(&w, v)
+	return w.b, err
+}
+
+// encodeValue writes the encoded value of v to w using a single call to Write.
+// The value is encoded into a pooled buffer using a.AppendEncodeValue.
+func encodeValue(w io.Writer, a Appender, v reflect.Value) error {
+	bp := encodeBufPool.Get().(*[]byte)
+	b, err := a.AppendEncodeValue((*bp)[:0], v)
+	if err == nil {
+		var n int
+		n, err = w.Write(b)
+		if err == nil && n != len(b) {
+			err = errors.New("unexpected number of bytes written")
+		}
+	}
+	if cap(b) <= maxPooledBufSize {
+		*bp = b
+		encodeBufPool.Put(bp)
+	}
+	return err
+}
+
 // PreDecode is a helper function that should be called by each Schema's Decode
 // routine. It removes exactly one level of indirection for v and reads the
 // null byte if nullable is set. If a null value is read, (true, nil) is
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"io/ioutil"
 	"log"
+	"net"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type embeddedStruct struct {
@@ -301,3 +303,122 @@ func TestFixedObjectSerializeJSON(t *testing.T) {
 	fixedObjectReader1(t, true)
 
 }
+
+type appendStruct struct {
+	ID      int64
+	Name    string
+	Code    string `schemer:"[4]"`
+	Score   float32
+	Active  bool
+	Small   uint8
+	Tags    []string
+	Point   [2]float64
+	Created *time.Time
+	Peer    net.IP
+	Nested  *embeddedStruct
+}
+
+// make sure that AppendEncode produces the same encoding as Encode and appends
+// values to a reused buffer without allocating
+func TestAppendEncode(t *testing.T) {
+
+	created := time.Unix(1600000000, 0)
+	int2 := int64(-7)
+	value := appendStruct{
+		ID:      -42,
+		Name:    "name",
+		Code:    "ab",
+		Score:   1.5,
+		Active:  true,
+		Small:   200,
+		Tags:    []string{"a", "bb"},
+		Point:   [2]float64{1, -1},
+		Created: &created,
+		Peer:    net.IPv4(192, 168, 0, 200).To4(),
+		Nested:  &embeddedStruct{Int1: 1, Int2: &int2},
+	}
+	values := []interface{}{
+		value,
+		&value,
+		int8(-3),
+		uint64(1) << 60,
+		"a string",
+		[]string{"x", "y"},
+		3 + 4i,
+		map[string]int{"a": 1},
+		[]interface{}{1, "two", nil},
+	}
+
+	prefix := []byte("prefix")
+	for _, v := range values {
+		s, err := SchemaOf(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, ok := s.(Appender)
+		if !ok {
+			t.Fatalf("%T does not implement Appender", s)
+		}
+
+		var expected bytes.Buffer
+		if err = s.Encode(&expected, v); err != nil {
+			t.Fatal(err)
+		}
+		b, err := a.AppendEncode(append([]byte(nil), prefix...), v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(b, prefix) || !bytes.Equal(b[len(prefix):], expected.Bytes()) {
+			t.Errorf("%T: AppendEncode differs from Encode", v)
+		}
+
+		decoded := reflect.New(reflect.TypeOf(v))
+		if err = s.Decode(bytes.NewReader(b[len(prefix):]), decoded.Interface()); err != nil {
+			t.Fatalf("%T: %v", v, err)
+		}
+		if v, ok := v.(appendStruct); ok {
+			d := decoded.Elem().Interface().(appendStruct)
+			if !d.Created.Equal(*v.Created) {
+				t.Errorf("unexpected time %v", d.Created)
+			}
+			d.Created = v.Created
+			d.Peer = d.Peer.To4()
+			if !reflect.DeepEqual(d, v) {
+				t.Errorf("expected %+v; got %+v", v, d)
+			}
+		}
+	}
+
+	// IPv4 address bytes are encoded as variable-size integers
+	var ipBytes bytes.Buffer
+	ipv4RawSchema.Encode(&ipBytes, [4]byte{192, 168, 0, 200})
+	s, _ := SchemaOf(value.Peer)
+	b, err := s.(Appender).AppendEncode(nil, value.Peer)
+	if err != nil || !bytes.Equal(b, ipBytes.Bytes()) {
+		t.Errorf("unexpected IPv4 encoding %v, %v", b, err)
+	}
+
+	s, err = SchemaOf(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := s.(Appender)
+	var i interface{} = &value
+	buf := make([]byte, 0, 256)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf, err = a.AppendEncode(buf[:0], i)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations; got %v", allocs)
+	}
+
+	if _, err = (&VarStringSchema{}).AppendEncode(nil, 1); err == nil {
+		t.Error("expected error encoding int with VarStringSchema")
+	}
+	if _, err = (&VarStringSchema{}).AppendEncode(nil, nil); err == nil {
+		t.Error("expected error encoding nil with non-nullable schema")
+	}
+}
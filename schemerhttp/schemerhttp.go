@@ -0,0 +1,536 @@
+// Package schemerhttp provides HTTP handlers and client helpers that exchange
+// schemer-encoded bodies (Content-Type application/x-schemer) and fall back to
+// JSON for clients that do not support schemer.
+//
+// The writer schema of a schemer body is sent in the Schemer-Schema header
+// (the base64-encoded binary schema), or if a SchemaRegistry is used, its
+// 64-bit fingerprint is sent in the Schemer-Schema-Fingerprint header
+// instead. JSON bodies include the same headers, so that JSON values can be
+// decoded into the schema's Go type.
+package schemerhttp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bminer/schemer"
+)
+
+const (
+	// ContentType is the media type of schemer-encoded bodies
+	ContentType = "application/x-schemer"
+
+	// SchemaHeader contains the base64-encoded writer schema of a body
+	SchemaHeader = "Schemer-Schema"
+
+	// FingerprintHeader contains the 64-bit fingerprint of the writer schema of
+	// a body as 16 hexadecimal digits (see schemer.Fingerprint64)
+	FingerprintHeader = "Schemer-Schema-Fingerprint"
+
+	jsonContentType = "application/json"
+
+	// accept is the Accept header sent by clients
+	accept = ContentType + ", " + jsonContentType + ";q=0.5"
+
+	// DefaultMaxBodySize is the maximum size of a body read by a Codec whose
+	// MaxBodySize is 0
+	DefaultMaxBodySize = 32 << 20
+)
+
+// Error is an error with an HTTP status code. It is returned when a request or
+// response cannot be read or written, and may be returned by the serve
+// function of a Handler to respond with a specific status code.
+type Error struct {
+	Code int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(code int, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// Codec reads and writes HTTP bodies. The zero value is ready to use and
+// always sends schemas inline.
+type Codec struct {
+	// Registry, if not nil, is used to register the schemas of written bodies
+	// and to look up the schemas of read bodies by fingerprint. Written bodies
+	// then only include the schema's fingerprint.
+	Registry schemer.SchemaRegistry
+
+	// MaxBodySize is the maximum size in bytes of a body read by the Codec,
+	// and of the Go type of the schema of a body. If it is 0,
+	// DefaultMaxBodySize is used.
+	MaxBodySize int64
+}
+
+// DefaultCodec is the Codec used by the package-level functions
+var DefaultCodec = &Codec{}
+
+// maxBodySize returns the maximum size of a body read by c
+func (c *Codec) maxBodySize() int64 {
+	if c.MaxBodySize <= 0 {
+		return DefaultMaxBodySize
+	}
+	return c.MaxBodySize
+}
+
+// setSchemaHeaders adds the schema headers for s to h
+func (c *Codec) setSchemaHeaders(h http.Header, s schemer.Schema) error {
+	if c.Registry != nil {
+		if _, err := c.Registry.Register(s); err != nil {
+			return err
+		}
+		fp, err := schemer.Fingerprint64(s)
+		if err != nil {
+			return err
+		}
+		h.Set(FingerprintHeader, fmt.Sprintf("%016x", fp))
+		return nil
+	}
+
+	m, ok := s.(schemer.Marshaler)
+	if !ok {
+		return fmt.Errorf("schema does not implement MarshalSchemer")
+	}
+	b, err := m.MarshalSchemer()
+	if err != nil {
+		return err
+	}
+	h.Set(SchemaHeader, base64.StdEncoding.EncodeToString(b))
+	return nil
+}
+
+// schemaOf returns the schema described by the headers h, or nil if h does
+// not include a schema
+func (c *Codec) schemaOf(h http.Header) (schemer.Schema, error) {
+	if str := h.Get(SchemaHeader); str != "" {
+		b, err := base64.StdEncoding.DecodeString(str)
+		if err != nil {
+			return nil, newError(http.StatusBadRequest, "invalid %s header: %v", SchemaHeader, err)
+		}
+		s, err := schemer.DecodeSchema(bytes.NewReader(b))
+		if err != nil {
+			return nil, newError(http.StatusBadRequest, "invalid %s header: %v", SchemaHeader, err)
+		}
+		return s, nil
+	}
+
+	if str := h.Get(FingerprintHeader); str != "" {
+		fp, err := strconv.ParseUint(str, 16, 64)
+		if err != nil {
+			return nil, newError(http.StatusBadRequest, "invalid %s header", FingerprintHeader)
+		}
+		if c.Registry == nil {
+			return nil, newError(http.StatusBadRequest, "unknown schema fingerprint %s", str)
+		}
+		_, s, err := c.Registry.LookupFingerprint(fp)
+		if errors.Is(err, schemer.ErrSchemaNotFound) {
+			return nil, newError(http.StatusBadRequest, "unknown schema fingerprint %s", str)
+		}
+		return s, err
+	}
+	return nil, nil
+}
+
+// writeBody writes the headers and the encoded value of v using the specified
+// media type (ContentType or JSON)
+func (c *Codec) writeBody(h http.Header, w io.Writer, mediaType string, v interface{}) (
+	func() error, error) {
+
+	s, err := schemer.SchemaOf(v)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.setSchemaHeaders(h, s); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if mediaType == ContentType {
+		err = s.Encode(&buf, v)
+	} else {
+		err = json.NewEncoder(&buf).Encode(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	h.Set("Content-Type", mediaType)
+	h.Set("Content-Length", strconv.Itoa(buf.Len()))
+
+	return func() error {
+		_, err := buf.WriteTo(w)
+		return err
+	}, nil
+}
+
+// goType returns the Go type of s, or an error if the type cannot be built or
+// is larger than max bytes. Schemas are read from untrusted headers, so their
+// Go types are checked before values of these types are allocated.
+func goType(s schemer.Schema, max int64) (t reflect.Type, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cannot build Go type: %v", r)
+		}
+	}()
+	t = s.GoType()
+	if uint64(t.Size()) > uint64(max) {
+		return nil, fmt.Errorf("Go type %v is larger than %d bytes", t, max)
+	}
+	return t, nil
+}
+
+// readAll reads a body of at most max bytes from r
+func readAll(r io.Reader, max int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, max+1))
+	// Note: http.MaxBytesReader returns an error once max bytes have been read
+	if int64(len(b)) > max || (err != nil && int64(len(b)) == max) {
+		return nil, newError(http.StatusRequestEntityTooLarge, "body is larger than %d bytes", max)
+	}
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "cannot read body: %v", err)
+	}
+	return b, nil
+}
+
+// readBody decodes a body with the specified headers and stores its value in
+// i, which must be a non-nil pointer
+func (c *Codec) readBody(h http.Header, body io.Reader, i interface{}) error {
+	mediaType := jsonContentType
+	if ct := h.Get("Content-Type"); ct != "" {
+		var err error
+		mediaType, _, err = mime.ParseMediaType(ct)
+		if err != nil {
+			return newError(http.StatusUnsupportedMediaType, "invalid Content-Type: %v", err)
+		}
+	}
+	if mediaType != ContentType && mediaType != jsonContentType {
+		return newError(http.StatusUnsupportedMediaType, "unsupported Content-Type %q", mediaType)
+	}
+
+	s, err := c.schemaOf(h)
+	if err != nil {
+		return err
+	}
+	var t reflect.Type
+	if s != nil {
+		if t, err = goType(s, c.maxBodySize()); err != nil {
+			return newError(http.StatusBadRequest, "invalid schema: %v", err)
+		}
+	}
+
+	// The body is read in full, so that the lengths of strings and arrays
+	// are checked against its size when it is decoded
+	b, err := readAll(body, c.maxBodySize())
+	if err != nil {
+		return err
+	}
+
+	if mediaType == ContentType {
+		if s == nil {
+			return newError(http.StatusBadRequest, "%s or %s header is required",
+				SchemaHeader, FingerprintHeader)
+		}
+		if err = decodeBytes(s, b, i); err != nil {
+			return newError(http.StatusBadRequest, "invalid body: %v", err)
+		}
+		return nil
+	}
+
+	// Decode JSON values into the schema's Go type rather than generic maps
+	// and slices if the destination is an empty interface
+	v := reflect.ValueOf(i)
+	if s != nil && v.Kind() == reflect.Ptr && !v.IsNil() &&
+		v.Elem().Kind() == reflect.Interface && v.Elem().NumMethod() == 0 {
+		dest := reflect.New(t)
+		if err = json.NewDecoder(bytes.NewReader(b)).Decode(dest.Interface()); err != nil {
+			return newError(http.StatusBadRequest, "invalid body: %v", err)
+		}
+		v.Elem().Set(dest.Elem())
+		return nil
+	}
+	if err = json.NewDecoder(bytes.NewReader(b)).Decode(i); err != nil {
+		return newError(http.StatusBadRequest, "invalid body: %v", err)
+	}
+	return nil
+}
+
+// decodeBytes decodes the value encoded with schema s in b and stores it in i
+func decodeBytes(s schemer.Schema, b []byte, i interface{}) error {
+	bd, ok := s.(schemer.BytesDecoder)
+	if !ok {
+		return s.Decode(bytes.NewReader(b), i)
+	}
+	n, err := bd.DecodeBytes(b, i)
+	if err == nil && n != len(b) {
+		err = fmt.Errorf("%d bytes of trailing data", len(b)-n)
+	}
+	return err
+}
+
+// ReadRequest decodes the body of r, which may be a schemer or JSON body, and
+// stores its value in i. If the Content-Type is not supported, an *Error with
+// code 415 (Unsupported Media Type) is returned; if the body is larger than
+// the Codec's MaxBodySize, an *Error with code 413 (Request Entity Too Large)
+// is returned.
+func (c *Codec) ReadRequest(r *http.Request, i interface{}) error {
+	return c.readBody(r.Header, http.MaxBytesReader(nil, r.Body, c.maxBodySize()), i)
+}
+
+// WriteResponse writes a response with the specified status code and the
+// value v. The body is encoded using schemer or JSON according to the Accept
+// header of r. If neither is acceptable, nothing is written and an *Error with
+// code 406 (Not Acceptable) is returned.
+func (c *Codec) WriteResponse(w http.ResponseWriter, r *http.Request, code int, v interface{}) error {
+	mediaType := negotiate(r.Header.Get("Accept"))
+	if mediaType == "" {
+		return newError(http.StatusNotAcceptable, "%s and %s are not acceptable",
+			ContentType, jsonContentType)
+	}
+	w.Header().Add("Vary", "Accept")
+
+	write, err := c.writeBody(w.Header(), w, mediaType, v)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(code)
+	return write()
+}
+
+// NewRequest returns a new request with a schemer-encoded body containing the
+// value v (or no body if v is nil) that accepts schemer and JSON responses
+func (c *Codec) NewRequest(method, url string, v interface{}) (*http.Request, error) {
+	if v == nil {
+		req, err := http.NewRequest(method, url, nil)
+		if err == nil {
+			req.Header.Set("Accept", accept)
+		}
+		return req, err
+	}
+
+	h := make(http.Header)
+	var buf bytes.Buffer
+	write, err := c.writeBody(h, &buf, ContentType, v)
+	if err != nil {
+		return nil, err
+	}
+	if err = write(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, url, &buf)
+	if err != nil {
+		return nil, err
+	}
+	for k, values := range h {
+		req.Header[k] = values
+	}
+	req.Header.Set("Accept", accept)
+	return req, nil
+}
+
+// ReadResponse decodes the body of resp and stores its value in i. If the
+// status code of resp does not indicate success, an *Error containing the
+// status code and the body of the response is returned. The body of resp is
+// not closed.
+func (c *Codec) ReadResponse(resp *http.Response, i interface{}) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		text := strings.TrimSpace(string(msg))
+		if text == "" {
+			text = resp.Status
+		}
+		return &Error{Code: resp.StatusCode, Err: errors.New(text)}
+	}
+	if i == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return c.readBody(resp.Header, resp.Body, i)
+}
+
+// Do sends a request with the value in (or no body if in is nil) using client
+// (or http.DefaultClient if nil) and decodes the response into out (unless
+// out is nil)
+func (c *Codec) Do(client *http.Client, method, url string, in, out interface{}) error {
+	req, err := c.NewRequest(method, url, in)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return c.ReadResponse(resp, out)
+}
+
+// Handler returns an http.Handler that decodes the body of each request into
+// the value returned by newRequest (a pointer, or nil if requests have no
+// body), calls serve, and writes the value returned by serve as the response.
+// If serve returns a nil value, the response has status 204 (No Content).
+// If serve returns an *Error, its status code is used; other errors result
+// in status 500 (Internal Server Error).
+func (c *Codec) Handler(newRequest func() interface{},
+	serve func(r *http.Request, req interface{}) (interface{}, error)) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Check the Accept header before doing any work
+		if negotiate(r.Header.Get("Accept")) == "" {
+			http.Error(w, "Not Acceptable", http.StatusNotAcceptable)
+			return
+		}
+
+		var req interface{}
+		if newRequest != nil {
+			req = newRequest()
+			if err := c.ReadRequest(r, req); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+
+		resp, err := serve(r, req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if resp == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if err = c.WriteResponse(w, r, http.StatusOK, resp); err != nil {
+			writeError(w, err)
+		}
+	})
+}
+
+// writeError writes err as a plain text response
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	var e *Error
+	if errors.As(err, &e) {
+		code = e.Code
+	}
+	http.Error(w, err.Error(), code)
+}
+
+// ReadRequest calls DefaultCodec.ReadRequest
+func ReadRequest(r *http.Request, i interface{}) error {
+	return DefaultCodec.ReadRequest(r, i)
+}
+
+// WriteResponse calls DefaultCodec.WriteResponse
+func WriteResponse(w http.ResponseWriter, r *http.Request, code int, v interface{}) error {
+	return DefaultCodec.WriteResponse(w, r, code, v)
+}
+
+// NewRequest calls DefaultCodec.NewRequest
+func NewRequest(method, url string, v interface{}) (*http.Request, error) {
+	return DefaultCodec.NewRequest(method, url, v)
+}
+
+// ReadResponse calls DefaultCodec.ReadResponse
+func ReadResponse(resp *http.Response, i interface{}) error {
+	return DefaultCodec.ReadResponse(resp, i)
+}
+
+// Do calls DefaultCodec.Do
+func Do(client *http.Client, method, url string, in, out interface{}) error {
+	return DefaultCodec.Do(client, method, url, in, out)
+}
+
+// Handler calls DefaultCodec.Handler
+func Handler(newRequest func() interface{},
+	serve func(r *http.Request, req interface{}) (interface{}, error)) http.Handler {
+	return DefaultCodec.Handler(newRequest, serve)
+}
+
+// negotiate returns the media type (ContentType or JSON) to use for a response
+// to a request with the specified Accept header, or "" if neither is
+// acceptable. The most specific matching media range determines the quality of
+// each media type. JSON is preferred if both are equally acceptable through
+// wildcards, since clients that do not mention schemer may not support it.
+func negotiate(header string) string {
+	if strings.TrimSpace(header) == "" {
+		return jsonContentType
+	}
+
+	type match struct {
+		q           float64
+		specificity int // 0 for */*, 1 for type/*, 2 for type/subtype
+	}
+	var best [2]match // quality of ContentType and JSON
+	candidates := [2]string{ContentType, jsonContentType}
+	for i := range best {
+		best[i].specificity = -1
+	}
+
+	for _, part := range strings.Split(header, ",") {
+		mediaRange, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if str, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(str, 64)
+			if err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		for i, c := range candidates {
+			specificity := -1
+			switch {
+			case mediaRange == c:
+				specificity = 2
+			case mediaRange == "application/*":
+				specificity = 1
+			case mediaRange == "*/*":
+				specificity = 0
+			}
+			if specificity > best[i].specificity {
+				best[i] = match{q: q, specificity: specificity}
+			}
+		}
+	}
+
+	schemerQ, jsonQ := best[0].q, best[1].q
+	if best[0].specificity < 0 {
+		schemerQ = 0
+	}
+	if best[1].specificity < 0 {
+		jsonQ = 0
+	}
+	switch {
+	case schemerQ == 0 && jsonQ == 0:
+		return ""
+	case schemerQ > jsonQ:
+		return ContentType
+	case jsonQ > schemerQ:
+		return jsonContentType
+	case best[0].specificity == 2 && best[1].specificity < 2:
+		// Equal quality, but only schemer was requested explicitly
+		return ContentType
+	default:
+		return jsonContentType
+	}
+}
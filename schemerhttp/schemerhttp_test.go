@@ -0,0 +1,229 @@
+package schemerhttp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bminer/schemer"
+)
+
+type point struct {
+	X, Y int
+	Name string
+}
+
+func newPointServer(c *Codec) *httptest.Server {
+	return httptest.NewServer(c.Handler(
+		func() interface{} { return &point{} },
+		func(r *http.Request, req interface{}) (interface{}, error) {
+			p := req.(*point)
+			if p.Name == "" {
+				return nil, &Error{Code: http.StatusUnprocessableEntity, Err: errors.New("name is required")}
+			}
+			if p.Name == "empty" {
+				return nil, nil
+			}
+			return point{X: p.Y, Y: p.X, Name: strings.ToUpper(p.Name)}, nil
+		},
+	))
+}
+
+func TestRoundTrip(t *testing.T) {
+
+	for _, c := range []*Codec{{}, {Registry: schemer.NewMemorySchemaRegistry()}} {
+		srv := newPointServer(c)
+
+		var out point
+		err := c.Do(srv.Client(), "POST", srv.URL, point{1, 2, "a"}, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := (point{2, 1, "A"}); out != expected {
+			t.Errorf("expected %v; got %v", expected, out)
+		}
+
+		// No Content
+		out = point{}
+		err = c.Do(srv.Client(), "POST", srv.URL, point{Name: "empty"}, &out)
+		if err != nil || out != (point{}) {
+			t.Errorf("unexpected result %v, %v", out, err)
+		}
+
+		// Errors returned by the handler
+		err = c.Do(srv.Client(), "POST", srv.URL, point{}, &out)
+		var e *Error
+		if !errors.As(err, &e) || e.Code != http.StatusUnprocessableEntity ||
+			e.Error() != "name is required" {
+			t.Errorf("unexpected error %v", err)
+		}
+		srv.Close()
+	}
+}
+
+func TestNegotiation(t *testing.T) {
+
+	srv := newPointServer(DefaultCodec)
+	defer srv.Close()
+
+	tests := []struct {
+		accept      string
+		code        int
+		contentType string
+	}{
+		{"", http.StatusOK, "application/json"},
+		{"*/*", http.StatusOK, "application/json"},
+		{"application/x-schemer", http.StatusOK, ContentType},
+		{"application/x-schemer, */*", http.StatusOK, ContentType},
+		{"application/json, application/x-schemer;q=0.9", http.StatusOK, "application/json"},
+		{"application/json;q=0.1, application/*", http.StatusOK, ContentType},
+		{"text/html", http.StatusNotAcceptable, ""},
+		{"*/*, application/json;q=0, application/x-schemer;q=0", http.StatusNotAcceptable, ""},
+	}
+	for _, test := range tests {
+		req, err := NewRequest("POST", srv.URL, point{1, 2, "a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept", test.accept)
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.code {
+			t.Errorf("Accept %q: expected status %d; got %d", test.accept, test.code, resp.StatusCode)
+			continue
+		}
+		if test.contentType != "" && resp.Header.Get("Content-Type") != test.contentType {
+			t.Errorf("Accept %q: expected %s; got %s", test.accept, test.contentType,
+				resp.Header.Get("Content-Type"))
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+
+	srv := newPointServer(DefaultCodec)
+	defer srv.Close()
+
+	// JSON request without a schema
+	req, err := http.NewRequest("POST", srv.URL, strings.NewReader(`{"X":3,"Y":4,"Name":"b"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "application/json" ||
+		resp.Header.Get(SchemaHeader) == "" {
+		t.Errorf("unexpected headers %v", resp.Header)
+	}
+
+	// The JSON response is decoded into the schema's Go type
+	var out interface{}
+	err = ReadResponse(resp, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Struct || v.FieldByName("X").Int() != 4 ||
+		v.FieldByName("Name").String() != "B" {
+		t.Errorf("unexpected value %#v", out)
+	}
+}
+
+func TestUnsupportedMediaType(t *testing.T) {
+
+	srv := newPointServer(DefaultCodec)
+	defer srv.Close()
+
+	for _, body := range []struct{ contentType, header string }{
+		{"text/plain", ""},
+		{ContentType, ""}, // missing schema
+		{ContentType, "!"},
+	} {
+		req, err := http.NewRequest("POST", srv.URL, bytes.NewReader([]byte{0}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", body.contentType)
+		if body.header != "" {
+			req.Header.Set(SchemaHeader, body.header)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		code := http.StatusBadRequest
+		if body.contentType == "text/plain" {
+			code = http.StatusUnsupportedMediaType
+		}
+		if resp.StatusCode != code {
+			t.Errorf("%+v: expected status %d; got %d", body, code, resp.StatusCode)
+		}
+	}
+}
+
+func TestUntrustedBodies(t *testing.T) {
+
+	srv := newPointServer(&Codec{MaxBodySize: 64})
+	defer srv.Close()
+
+	schemaHeader := func(s schemer.Schema) string {
+		b, err := s.(schemer.Marshaler).MarshalSchemer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	tags, err := schemer.SchemaOf(struct{ Tags []string }{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1<<40 as an unsigned varint
+	hugeLen := []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x20}
+
+	for _, test := range []struct {
+		name   string
+		schema schemer.Schema
+		body   []byte
+		code   int
+	}{
+		{"unbuildable Go type", &schemer.VarObjectSchema{
+			Key:   &schemer.VarArraySchema{Element: &schemer.BoolSchema{}},
+			Value: &schemer.BoolSchema{},
+		}, []byte{0}, http.StatusBadRequest},
+		{"huge Go type", &schemer.FixedArraySchema{
+			Length:  1 << 30,
+			Element: &schemer.BoolSchema{},
+		}, []byte{0}, http.StatusBadRequest},
+		{"huge array length", tags, hugeLen, http.StatusBadRequest},
+		{"large body", tags, bytes.Repeat([]byte{0}, 65), http.StatusRequestEntityTooLarge},
+	} {
+		req, err := http.NewRequest("POST", srv.URL, bytes.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", ContentType)
+		req.Header.Set(SchemaHeader, schemaHeader(test.schema))
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.code {
+			t.Errorf("%s: expected status %d; got %d", test.name, test.code, resp.StatusCode)
+		}
+	}
+}
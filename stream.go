@@ -0,0 +1,248 @@
+package schemer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// An Encoder writes a stream of values to an io.Writer. Like encoding/gob,
+// each distinct schema is sent only once per stream along with a stream-local
+// ID; subsequent values encoded with the same schema only include the ID.
+// A stream consists of a sequence of items, each starting with a signed
+// variable-size integer:
+//   - A negative number -n is followed by the encoded schema with ID n
+//     (see MarshalSchemer)
+//   - A positive number n is followed by a value encoded with the schema with
+//     ID n
+//
+// An Encoder is safe for concurrent use by multiple goroutines.
+type Encoder struct {
+	mu     sync.Mutex
+	w      io.Writer
+	buf    []byte
+	types  map[reflect.Type]Schema
+	ids    map[string]int64 // encoded schema => ID
+	sent   map[Schema]int64 // schemas that are pointers => ID
+	lastID int64
+}
+
+// NewEncoder returns a new Encoder that writes to w
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w:     w,
+		types: make(map[reflect.Type]Schema),
+		ids:   make(map[string]int64),
+		sent:  make(map[Schema]int64),
+	}
+}
+
+// Encode writes v to the stream using the schema of its type (see SchemaOf)
+func (enc *Encoder) Encode(v interface{}) error {
+	return enc.EncodeValue(reflect.ValueOf(v))
+}
+
+// EncodeValue writes v to the stream using the schema of its type (see
+// SchemaOf)
+func (enc *Encoder) EncodeValue(v reflect.Value) error {
+	if !v.IsValid() {
+		return fmt.Errorf("cannot encode nil value")
+	}
+
+	enc.mu.Lock()
+	defer enc.mu.Unlock()
+
+	t := v.Type()
+	s, ok := enc.types[t]
+	if !ok {
+		var err error
+		s, err = SchemaOfType(t)
+		if err != nil {
+			return err
+		}
+		enc.types[t] = s
+	}
+	return enc.encode(s, v)
+}
+
+// EncodeSchema writes v to the stream using schema s. s must not be modified
+// after it has been used to encode a value.
+func (enc *Encoder) EncodeSchema(s Schema, v interface{}) error {
+	if s == nil {
+		return fmt.Errorf("cannot encode value with nil schema")
+	}
+
+	enc.mu.Lock()
+	defer enc.mu.Unlock()
+
+	return enc.encode(s, reflect.ValueOf(v))
+}
+
+// encode writes v to the stream, preceded by s if it has not yet been sent.
+// The schema and value are written using a single call to Write.
+// enc.mu must be held.
+func (enc *Encoder) encode(s Schema, v reflect.Value) error {
+	// The IDs of schemas that are pointers are remembered, so that s need
+	// not be marshaled again to find its ID
+	ptr := reflect.ValueOf(s).Kind() == reflect.Ptr
+	id, sent := int64(0), false
+	if ptr {
+		id, sent = enc.sent[s]
+	}
+
+	b := enc.buf[:0]
+	var schemaBytes []byte
+	if !sent {
+		m, ok := s.(Marshaler)
+		if !ok {
+			return fmt.Errorf("schema does not implement MarshalSchemer")
+		}
+		var err error
+		schemaBytes, err = m.MarshalSchemer()
+		if err != nil {
+			return err
+		}
+
+		// An equivalent schema may have been sent
+		id, sent = enc.ids[string(schemaBytes)]
+		if !sent {
+			id = enc.lastID + 1
+			b = appendVarint(b, -id)
+			b = append(b, schemaBytes...)
+		}
+	}
+	b = appendVarint(b, id)
+
+	b, err := appendEncodeValue(s, b, v)
+	enc.buf = b
+	if err != nil {
+		return err
+	}
+
+	_, err = enc.w.Write(b)
+	if err != nil {
+		return err
+	}
+	if !sent {
+		enc.ids[string(schemaBytes)] = id
+		enc.lastID = id
+	}
+	if ptr {
+		enc.sent[s] = id
+	}
+	return nil
+}
+
+// appendVarint appends the signed variable-size integer x to b
+func appendVarint(b []byte, x int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], x)
+	return append(b, buf[:n]...)
+}
+
+// A Decoder reads a stream of values written by an Encoder.
+// If the underlying reader does not implement io.ByteReader, it is buffered,
+// so the Decoder may read data beyond the values it returns.
+// A Decoder is safe for concurrent use by multiple goroutines.
+type Decoder struct {
+	mu      sync.Mutex
+	r       decodeReader
+	schemas map[int64]Schema
+
+	// pending is the schema of the next value if it was returned by Schema
+	pending Schema
+}
+
+// NewDecoder returns a new Decoder that reads from r
+func NewDecoder(r io.Reader) *Decoder {
+	dr, ok := r.(decodeReader)
+	if !ok {
+		dr = bufio.NewReader(r)
+	}
+	return &Decoder{
+		r:       dr,
+		schemas: make(map[int64]Schema),
+	}
+}
+
+// Decode reads the next value from the stream and stores it in i. io.EOF is
+// returned if there are no more values.
+func (dec *Decoder) Decode(i interface{}) error {
+	if i == nil {
+		return fmt.Errorf("cannot decode to nil destination")
+	}
+	return dec.DecodeValue(reflect.ValueOf(i))
+}
+
+// DecodeValue reads the next value from the stream and stores it in v. io.EOF
+// is returned if there are no more values.
+func (dec *Decoder) DecodeValue(v reflect.Value) error {
+	dec.mu.Lock()
+	defer dec.mu.Unlock()
+
+	s, err := dec.next()
+	if err != nil {
+		return err
+	}
+	return s.DecodeValue(dec.r, v)
+}
+
+// Schema reads schema definitions from the stream until it reaches the next
+// value and returns the schema of that value without reading the value, which
+// is then read by the next call to Decode or DecodeValue. io.EOF is returned if
+// there are no more values.
+func (dec *Decoder) Schema() (Schema, error) {
+	dec.mu.Lock()
+	defer dec.mu.Unlock()
+
+	s, err := dec.next()
+	if err != nil {
+		return nil, err
+	}
+	dec.pending = s
+	return s, nil
+}
+
+// next reads schema definitions until it reaches the next value and returns
+// the schema of that value. dec.mu must be held.
+func (dec *Decoder) next() (Schema, error) {
+	if s := dec.pending; s != nil {
+		dec.pending = nil
+		return s, nil
+	}
+
+	for {
+		id, err := binary.ReadVarint(dec.r)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case id < 0:
+			if _, ok := dec.schemas[-id]; ok {
+				return nil, fmt.Errorf("schema %d was already defined", -id)
+			}
+			s, err := DecodeSchema(dec.r)
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return nil, err
+			}
+			dec.schemas[-id] = s
+
+		case id > 0:
+			s, ok := dec.schemas[id]
+			if !ok {
+				return nil, fmt.Errorf("value refers to undefined schema %d", id)
+			}
+			return s, nil
+
+		default:
+			return nil, fmt.Errorf("invalid schema ID 0")
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package schemer
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+
+	values := []testRecord{
+		{ID: 1, Name: "one", Tags: []string{"a"}},
+		{ID: 2, Name: "two", Tags: []string{"b", "c"}, Inner: &embeddedStruct{Int1: 2}},
+	}
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := buf.Len()
+	if err := enc.Encode("hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.EncodeSchema(&VarStringSchema{}, "world"); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(values[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// the struct schema is only sent once
+	s, err := SchemaOf(values[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemaBytes, err := s.(Marshaler).MarshalSchemer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(buf.Bytes(), schemaBytes); n != 1 {
+		t.Errorf("expected schema to be sent once; sent %d times", n)
+	}
+	if buf.Len()-first > 2*len(schemaBytes) {
+		t.Errorf("unexpected stream length %d", buf.Len())
+	}
+
+	dec := NewDecoder(&buf)
+	for _, expected := range values {
+		var v testRecord
+		if err = dec.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, expected) {
+			t.Errorf("expected %v; got %v", expected, v)
+		}
+	}
+
+	ds, err := dec.Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ds.(*VarStringSchema); !ok {
+		t.Errorf("unexpected schema %v", ds)
+	}
+	for _, expected := range []string{"hello", "world"} {
+		var str string
+		if err = dec.Decode(&str); err != nil || str != expected {
+			t.Errorf("expected %q; got %q, %v", expected, str, err)
+		}
+	}
+
+	// decode to an empty interface
+	var i interface{}
+	if err = dec.Decode(&i); err != nil {
+		t.Fatal(err)
+	}
+	if reflect.ValueOf(i).Elem().FieldByName("ID").Int() != 1 {
+		t.Errorf("unexpected value %v", i)
+	}
+
+	if err = dec.Decode(&i); err != io.EOF {
+		t.Errorf("expected io.EOF; got %v", err)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if err := enc.Encode(nil); err == nil {
+		t.Error("expected error encoding nil")
+	}
+	if err := enc.EncodeSchema(&BoolSchema{}, "x"); err == nil {
+		t.Error("expected error encoding string as bool")
+	}
+	if err := enc.Encode(true); err != nil {
+		t.Fatal(err)
+	}
+
+	// the failed value must not leave a schema definition on the stream
+	dec := NewDecoder(bytes.NewReader(buf.Bytes()))
+	var b bool
+	if err := dec.Decode(&b); err != nil || !b {
+		t.Errorf("unexpected value %v, %v", b, err)
+	}
+
+	// values that refer to undefined schemas
+	dec = NewDecoder(bytes.NewReader(buf.Bytes()[2:]))
+	if err := dec.Decode(&b); err == nil {
+		t.Error("expected error decoding undefined schema")
+	}
+}
+
+// marshalCountingSchema counts the number of times it is marshaled
+type marshalCountingSchema struct {
+	VarStringSchema
+	calls int
+}
+
+func (s *marshalCountingSchema) MarshalSchemer() ([]byte, error) {
+	s.calls++
+	return s.VarStringSchema.MarshalSchemer()
+}
+
+// the schema ID is remembered, so the schema is only marshaled once
+func TestStreamSchemaID(t *testing.T) {
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	s := &marshalCountingSchema{}
+	for _, str := range []string{"a", "b", "c"} {
+		if err := enc.EncodeSchema(s, str); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.calls != 1 {
+		t.Errorf("expected schema to be marshaled once; got %d", s.calls)
+	}
+
+	dec := NewDecoder(&buf)
+	for _, expected := range []string{"a", "b", "c"} {
+		var str string
+		if err := dec.Decode(&str); err != nil || str != expected {
+			t.Errorf("expected %q; got %q, %v", expected, str, err)
+		}
+	}
+}
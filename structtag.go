@@ -16,20 +16,25 @@ type StructTag struct {
 	NullableSet     bool     // if true, then the null struct tags is present on this field
 	WeakDecoding    bool     // does this field allow weak decoding?
 	WeakDecodingSet bool     // if true, weak decoding was present in the struct tag for this field
+	Default         string   // default value of this field (see ObjectField.Default)
+	DefaultSet      bool     // if true, a default value was present in the struct tag for this field
 }
 
 const tagAlias = `[A-Za-z0-9_]+`
 const tagAliases = `\[(` + tagAlias + `(?:,` + tagAlias + `)*)\]`
-const tagOpt = `(?:weak|null)`
+const tagOpt = `(?:\!?(?:weak|null)|default=[^,]*)`
 
-// ^(\-|([A-Za-z0-9_]+)|\[([A-Za-z0-9_]+(?:,[A-Za-z0-9_]+)*)\])?(,\!?(?:weak|null))*$
+// structTagAliasRegex matches aliases that can be written in a struct tag
+var structTagAliasRegex = regexp.MustCompile(`^` + tagAlias + `$`)
+
+// ^(\-|([A-Za-z0-9_]+)|\[([A-Za-z0-9_]+(?:,[A-Za-z0-9_]+)*)\])?((?:,(?:\!?(?:weak|null)|default=[^,]*))*)$
 // Note: non-capturing groups use regex syntax (?:   ...   )
 // Group 1 = Alias / Aliases
 // Group 2 = Single alias
 // Group 3 = Multiple aliases (comma-delimited)
 // Group 4 = Options (comma-delimited; prefixed with a comma)
 var structTagRegex *regexp.Regexp = regexp.MustCompile(
-	`^(\-|(` + tagAlias + `)|` + tagAliases + `)?(,\!?` + tagOpt + `)*$`,
+	`^(\-|(` + tagAlias + `)|` + tagAliases + `)?((?:,` + tagOpt + `)*)$`,
 )
 
 // ParseStructTag parses a struct tag string and returns a decoded StructTag
@@ -38,7 +43,9 @@ var structTagRegex *regexp.Regexp = regexp.MustCompile(
 // alias := "-" |
 //          identifier |
 //          "[" identifier ("," identifier)* "]"
-// option := "!" ? ( "weak" | "null" )
+// option := "!" ? ( "weak" | "null" ) |
+//           "default=" value
+// value := any sequence of characters other than ","
 // If the
 func ParseStructTag(s string) (tag StructTag) {
 	// See `structTagRegex` documentation
@@ -82,6 +89,11 @@ func ParseStructTag(s string) (tag StructTag) {
 			case "!weak":
 				tag.WeakDecoding = false
 				tag.WeakDecodingSet = true
+			default:
+				if strings.HasPrefix(opt, "default=") {
+					tag.Default = strings.TrimPrefix(opt, "default=")
+					tag.DefaultSet = true
+				}
 			}
 		}
 	}
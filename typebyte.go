@@ -2,10 +2,17 @@ package schemer
 
 // Collection of bit masks and values for the type byte of an encoded schema
 const (
-	NullMask     = 0x80 // nullable bit
+	NullMask = 0x80 // nullable bit
+
+	// Legacy custom schemas are 0b1nn 0000 where n is a custom schema
+	// identifier. These are still decoded, but no longer encoded.
 	CustomMask   = 0x40 // custom schema bit
 	CustomIDMask = 0x3F // custom schema identifier
 
+	// Custom types are 0b011 1111 followed by a 16-byte UUID and the raw schema
+	CustomTypeMask = 0x7F
+	CustomTypeByte = 0x3F
+
 	// FixedInt is 0b000 nnns where s is the signed/unsigned bit and
 	// n represents the encoded integer size in (8 << n) bits.
 	FixedIntMask     = 0x70
@@ -32,9 +39,13 @@ const (
 	EnumMask = 0x7F // 0b001 1101
 	EnumByte = 0x1D
 
-	StringMask      = 0x7F // 0b010 000f where f indicates fixed-length string
+	// String is 0b010 00bf where b indicates a binary string (i.e. []byte) and f
+	// indicates a fixed-length string
+	StringMask      = 0x7F
 	VarStringByte   = 0x20
 	FixedStringByte = 0x21
+	VarBytesByte    = 0x22
+	FixedBytesByte  = 0x23
 
 	ArrayMask      = 0x7F // 0b010 010f where f indicates fixed-length array
 	VarArrayByte   = 0x24
@@ -44,4 +55,10 @@ const (
 	ObjectMask      = 0x7F
 	VarObjectByte   = 0x28
 	FixedObjectByte = 0x29
+
+	VariantMask = 0x7F // 0b010 1100
+	VariantByte = 0x2C
+
+	SchemaMask = 0x7F // 0b010 1101
+	SchemaByte = 0x2D
 )
@@ -17,13 +17,11 @@ type byter struct {
 
 func (r byter) ReadByte() (byte, error) {
 	var buf [1]byte
-	n, err := r.Reader.Read(buf[:])
+	// Note: io.ReadFull keeps the byte if Read returns it along with io.EOF
+	_, err := io.ReadFull(r.Reader, buf[:])
 	if err != nil {
 		return 0, err
 	}
-	if n == 0 {
-		return 0, io.ErrNoProgress
-	}
 	return buf[0], nil
 }
 
@@ -31,36 +29,56 @@ func (r byter) ReadVarint() (int64, error) {
 	return binary.ReadVarint(r)
 }
 
-// ReadUvarint reads an Uvarint from r one byte at a time
-func ReadUvarint(r io.Reader) (uint64, error) {
+// recordingReader is a decodeReader that records the bytes read from r
+type recordingReader struct {
+	r decodeReader
+	b []byte
+}
 
-	rb := byter{r}
-	buf := make([]byte, binary.MaxVarintLen64)
+func (rr *recordingReader) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	rr.b = append(rr.b, p[:n]...)
+	return n, err
+}
 
-	// Read first byte into `buf`
-	b, err := rb.ReadByte()
-	if err != nil {
-		return 0, err
+func (rr *recordingReader) ReadByte() (byte, error) {
+	c, err := rr.r.ReadByte()
+	if err == nil {
+		rr.b = append(rr.b, c)
+	}
+	return c, err
+}
+
+// ReadUvarint reads an Uvarint from r one byte at a time. If r implements
+// io.ByteReader, its ReadByte method is used.
+func ReadUvarint(r io.Reader) (uint64, error) {
+
+	rb, ok := r.(io.ByteReader)
+	if !ok {
+		rb = byter{r}
 	}
-	buf[0] = b
 
-	// Read subsequent bytes into `buf`
-	i := 1
-	for ; b&0x80 > 0; i++ {
-		b, err = rb.ReadByte()
+	var x uint64
+	var shift uint
+	for i := 0; i < binary.MaxVarintLen64; i++ {
+		b, err := rb.ReadByte()
 		if err != nil {
+			if i > 0 && err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
 			return 0, err
 		}
-		buf[i] = b
-	}
-
-	decodedUInt, n := binary.Uvarint(buf)
-	if n != i {
-		return 0, fmt.Errorf("uvarint did not consume expected number of bytes")
+		if b < 0x80 {
+			if i == binary.MaxVarintLen64-1 && b > 1 {
+				break
+			}
+			return x | uint64(b)<<shift, nil
+		}
+		x |= uint64(b&0x7f) << shift
+		shift += 7
 	}
 
-	return decodedUInt, nil
-
+	return 0, fmt.Errorf("uvarint overflows a 64-bit integer")
 }
 
 // WriteUvarint writes v to w as an Uvarint
@@ -78,32 +78,41 @@ func (s *VarArraySchema) Encode(w io.Writer, i interface{}) error {
 
 // EncodeValue uses the schema to write the encoded value of v to the output stream
 func (s *VarArraySchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
+
+// AppendEncode appends the encoded value of i to dst and returns the extended slice
+func (s *VarArraySchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
 
-	done, err := PreEncode(w, &v, s.Nullable())
+// AppendEncodeValue appends the encoded value of v to dst and returns the extended slice
+func (s *VarArraySchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
+
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
 	if err != nil || done {
-		return err
+		return dst, err
 	}
 
-	t := v.Type()
-	k := t.Kind()
-
-	if k != reflect.Slice {
-		return fmt.Errorf("VarArraySchema can only encode slices")
+	if v.Kind() != reflect.Slice {
+		return dst, fmt.Errorf("VarArraySchema can only encode slices")
 	}
 
-	err = WriteUvarint(w, uint64(v.Len()))
-	if err != nil {
-		return errors.New("cannot encode var string length as var int")
+	dst = appendUvarint(dst, uint64(v.Len()))
+
+	// slices of fixed-width numbers are copied in one pass
+	if l, ok := bulkLayoutOf(s.Element, v.Type().Elem()); ok {
+		return appendBulk(dst, v, l), nil
 	}
 
 	for i := 0; i < v.Len(); i++ {
-		err := s.Element.Encode(w, v.Index(i).Interface())
+		dst, err = appendEncodeValue(s.Element, dst, v.Index(i))
 		if err != nil {
-			return err
+			return dst, err
 		}
 	}
 
-	return nil
+	return dst, nil
 }
 
 // Decode uses the schema to read the next encoded value from the input stream and store it in i
@@ -114,6 +123,11 @@ func (s *VarArraySchema) Decode(r io.Reader, i interface{}) error {
 	return s.DecodeValue(r, reflect.ValueOf(i))
 }
 
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *VarArraySchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
 // DecodeValue uses the schema to read the next encoded value from the input stream and store it in v
 func (s *VarArraySchema) DecodeValue(r io.Reader, v reflect.Value) error {
 
@@ -142,17 +156,37 @@ func (s *VarArraySchema) DecodeValue(r io.Reader, v reflect.Value) error {
 		return err
 	}
 
+	l, bulk := bulkLayoutOf(s.Element, t.Elem())
+
 	if v.IsNil() {
 		if !v.CanSet() {
 			return errors.New("v not settable")
 		}
-		v.Set(reflect.MakeSlice(t, int(expectedLen), int(expectedLen)))
+		var slice reflect.Value
+		if bulk {
+			slice, err = readBulkSlice(r, t, expectedLen, l)
+		} else {
+			slice, err = decodeSlice(r, t, expectedLen, minEncodedSize(s.Element),
+				func(v reflect.Value) error {
+					return s.Element.DecodeValue(r, v)
+				})
+		}
+		if err != nil {
+			return err
+		}
+		v.Set(slice)
+		return nil
 	}
 
 	// else we have an existing slice
 	// right now by default, we will just keep their entries
 	// but we have to decide if this behavior is OK??
 
+	// slices of fixed-width numbers are read in one pass
+	if bulk && uint64(v.Len()) == expectedLen {
+		return readBulk(r, v, l)
+	}
+
 	for i := 0; i < v.Len(); i++ {
 		err := s.Element.DecodeValue(r, v.Index(i))
 		if err != nil {
@@ -0,0 +1,212 @@
+package schemer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"unsafe"
+)
+
+// VarBytesSchema is a Schema for variable-length binary strings (i.e. []byte
+// values). Values are encoded as their length followed by the raw bytes.
+type VarBytesSchema struct {
+	SchemaOptions
+}
+
+func (s *VarBytesSchema) GoType() reflect.Type {
+	var t []byte
+	retval := reflect.TypeOf(t)
+
+	if s.Nullable() {
+		retval = reflect.PtrTo(retval)
+	}
+
+	return retval
+}
+
+func (s *VarBytesSchema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"type":     "bytes",
+		"nullable": s.Nullable(),
+	})
+}
+
+// MarshalSchemer encodes the schema in a portable binary format
+func (s *VarBytesSchema) MarshalSchemer() ([]byte, error) {
+
+	// bytes schemas are 1 byte long
+	var schema []byte = []byte{VarBytesByte}
+
+	// The most signifiant bit indicates whether or not the type is nullable
+	if s.Nullable() {
+		schema[0] |= NullMask
+	}
+
+	return schema, nil
+}
+
+// Encode uses the schema to write the encoded value of i to the output stream
+func (s *VarBytesSchema) Encode(w io.Writer, i interface{}) error {
+	return s.EncodeValue(w, reflect.ValueOf(i))
+}
+
+// EncodeValue uses the schema to write the encoded value of v to the output stream
+func (s *VarBytesSchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
+
+// AppendEncode appends the encoded value of i to dst and returns the extended slice
+func (s *VarBytesSchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
+
+// AppendEncodeValue appends the encoded value of v to dst and returns the
+// extended slice. v may be a byte slice, a byte array, or a string.
+func (s *VarBytesSchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
+
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
+	if err != nil || done {
+		return dst, err
+	}
+
+	if !isBytesKind(v) {
+		return dst, fmt.Errorf("VarBytesSchema only supports encoding byte slices, byte arrays, and strings")
+	}
+
+	dst = appendUvarint(dst, uint64(v.Len()))
+	return appendBytesValue(dst, v), nil
+}
+
+// Decode uses the schema to read the next encoded value from the input stream and store it in i
+func (s *VarBytesSchema) Decode(r io.Reader, i interface{}) error {
+	if i == nil {
+		return fmt.Errorf("cannot decode to nil destination")
+	}
+	return s.DecodeValue(r, reflect.ValueOf(i))
+}
+
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *VarBytesSchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
+// DecodeValue uses the schema to read the next encoded value from the input
+// stream and store it in v. Values can be decoded to byte slices, byte arrays
+// of the same length, and strings (with weak decoding).
+func (s *VarBytesSchema) DecodeValue(r io.Reader, v reflect.Value) error {
+
+	done, err := PreDecode(r, &v, s.Nullable())
+	if err != nil || done {
+		return err
+	}
+
+	if v.Kind() == reflect.Interface {
+		v.Set(reflect.New(s.GoType()))
+
+		v = v.Elem().Elem()
+	}
+
+	n, err := ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+
+	return decodeBytesValue(r, n, v, s.WeakDecoding())
+}
+
+// isBytesKind returns true if v is a byte slice, a byte array, or a string
+func isBytesKind(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		return v.Type().Elem().Kind() == reflect.Uint8
+	case reflect.String:
+		return true
+	}
+	return false
+}
+
+// appendBytesValue appends the bytes of v to dst. v must satisfy isBytesKind.
+func appendBytesValue(dst []byte, v reflect.Value) []byte {
+	switch {
+	case v.Kind() == reflect.String:
+		return append(dst, v.String()...)
+	case v.Kind() == reflect.Slice:
+		return append(dst, v.Bytes()...)
+	case v.CanAddr():
+		return append(dst, v.Slice(0, v.Len()).Bytes()...)
+	}
+	// unaddressable arrays are copied one byte at a time
+	for i := 0; i < v.Len(); i++ {
+		dst = append(dst, byte(v.Index(i).Uint()))
+	}
+	return dst
+}
+
+// nextBytes reads the next n bytes from r. If r is a sliceReader, the length
+// is checked against the remaining input before anything is allocated, and the
+// returned slice refers to the input (shared is true).
+func nextBytes(r io.Reader, n uint64) (b []byte, shared bool, err error) {
+	if sr, ok := r.(*sliceReader); ok {
+		b, err = sr.next(n)
+		return b, true, err
+	}
+
+	b, err = readN(r, n)
+	return b, false, err
+}
+
+// decodeBytesValue reads a binary string of n bytes from r and stores it in v,
+// which must be settable
+func decodeBytesValue(r io.Reader, n uint64, v reflect.Value, weak bool) error {
+
+	// Note: the value is read before checking the destination, so that the
+	// input stream is left at the start of the next value
+	b, shared, err := nextBytes(r, n)
+	if err != nil {
+		return err
+	}
+
+	k := v.Kind()
+	isByteElem := (k == reflect.Slice || k == reflect.Array) &&
+		v.Type().Elem().Kind() == reflect.Uint8
+	if !isByteElem && k != reflect.String {
+		return fmt.Errorf("binary strings can only be decoded to byte slices, byte arrays, and strings")
+	}
+	if k == reflect.String && !weak {
+		return fmt.Errorf("cannot decode binary string to string without weak decoding")
+	}
+	if k == reflect.Array && v.Len() != len(b) {
+		return fmt.Errorf("cannot decode binary string of length %d to array of length %d", len(b), v.Len())
+	}
+
+	// Ensure v is settable
+	if !v.CanSet() {
+		return fmt.Errorf("decode destination is not settable")
+	}
+
+	alias := shared && r.(*sliceReader).alias
+	switch k {
+	case reflect.Slice:
+		switch {
+		case alias:
+			v.SetBytes(b[:len(b):len(b)])
+		case shared:
+			dst := make([]byte, len(b))
+			copy(dst, b)
+			v.SetBytes(dst)
+		default:
+			v.SetBytes(b)
+		}
+	case reflect.Array:
+		copy(v.Slice(0, v.Len()).Bytes(), b)
+	case reflect.String:
+		if alias && len(b) > 0 {
+			v.SetString(*(*string)(unsafe.Pointer(&b)))
+		} else {
+			v.SetString(string(b))
+		}
+	}
+
+	return nil
+}
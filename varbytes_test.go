@@ -0,0 +1,238 @@
+package schemer
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestVarBytes1 checks that byte slices use a VarBytesSchema and that the
+// schema survives the binary and JSON encodings
+func TestVarBytes1(t *testing.T) {
+
+	s, err := SchemaOf([]byte("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(*VarBytesSchema); !ok {
+		t.Fatalf("expected *VarBytesSchema; got %T", s)
+	}
+
+	schema := &VarBytesSchema{SchemaOptions{nullable: true}}
+	b, err := schema.MarshalSchemer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != VarBytesByte|NullMask {
+		t.Errorf("unexpected type byte %#x", b[0])
+	}
+	tmp, err := DecodeSchema(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tmp, schema) {
+		t.Errorf("expected %#v; got %#v", schema, tmp)
+	}
+
+	j, err := schema.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp, err = DecodeSchemaJSON(bytes.NewReader(j))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tmp, schema) {
+		t.Errorf("expected %#v; got %#v", schema, tmp)
+	}
+}
+
+func TestVarBytes2(t *testing.T) {
+
+	schema := &VarBytesSchema{}
+	value := []byte{0, 1, 2, 0xFF}
+
+	var buf bytes.Buffer
+	if err := schema.Encode(&buf, value); err != nil {
+		t.Fatal(err)
+	}
+	// the length followed by the raw bytes
+	expected := []byte{4, 0, 1, 2, 0xFF}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("expected %v; got %v", expected, buf.Bytes())
+	}
+
+	var decoded []byte
+	if err := schema.Decode(bytes.NewReader(expected), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, value) {
+		t.Errorf("expected %v; got %v", value, decoded)
+	}
+
+	var arr [4]byte
+	if err := schema.Decode(bytes.NewReader(expected), &arr); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(arr[:], value) {
+		t.Errorf("expected %v; got %v", value, arr)
+	}
+
+	var short [3]byte
+	if err := schema.Decode(bytes.NewReader(expected), &short); err == nil {
+		t.Error("expected error decoding to array of the wrong length")
+	}
+
+	var i interface{}
+	if err := schema.Decode(bytes.NewReader(expected), &i); err != nil {
+		t.Fatal(err)
+	}
+	// like other schemas, values are decoded to a pointer
+	if p, ok := i.(*[]byte); !ok || !bytes.Equal(*p, value) {
+		t.Errorf("expected %v; got %#v", value, i)
+	}
+
+	// strings can only be decoded with weak decoding
+	var str string
+	if err := schema.Decode(bytes.NewReader(expected), &str); err == nil {
+		t.Error("expected error decoding to string without weak decoding")
+	}
+	weak := &VarBytesSchema{SchemaOptions{weakDecoding: true}}
+	if err := weak.Decode(bytes.NewReader(expected), &str); err != nil {
+		t.Fatal(err)
+	}
+	if str != string(value) {
+		t.Errorf("expected %q; got %q", value, str)
+	}
+}
+
+func TestVarBytes3(t *testing.T) {
+
+	schema := &VarBytesSchema{SchemaOptions{nullable: true}}
+
+	var buf bytes.Buffer
+	var nilValue *[]byte
+	if err := schema.Encode(&buf, nilValue); err != nil {
+		t.Fatal(err)
+	}
+	value := []byte("hello")
+	if err := schema.Encode(&buf, &value); err != nil {
+		t.Fatal(err)
+	}
+
+	r := bytes.NewReader(buf.Bytes())
+	decoded := &[]byte{}
+	if err := schema.Decode(r, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != nil {
+		t.Errorf("expected nil; got %v", decoded)
+	}
+	if err := schema.Decode(r, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded == nil || !bytes.Equal(*decoded, value) {
+		t.Errorf("expected %v; got %v", value, decoded)
+	}
+}
+
+// TestVarBytes4 checks that large blobs are encoded without per-byte overhead
+func TestVarBytes4(t *testing.T) {
+
+	value := bytes.Repeat([]byte{0x80, 'x'}, 1<<19)
+	s, err := SchemaOf(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Compile(s, reflect.TypeOf(value))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []Schema{s, p} {
+		var buf bytes.Buffer
+		if err := s.Encode(&buf, value); err != nil {
+			t.Fatal(err)
+		}
+		if n := buf.Len() - len(value); n != 3 {
+			t.Errorf("%T: expected 3 bytes of overhead; got %d", s, n)
+		}
+
+		var decoded []byte
+		if err := s.Decode(&buf, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decoded, value) {
+			t.Errorf("%T: decoded value does not match", s)
+		}
+	}
+}
+
+func TestVarBytesUnsafeDecodeBytes(t *testing.T) {
+
+	type blob struct {
+		Name string
+		Data []byte
+	}
+	value := blob{Name: "name", Data: []byte("data")}
+	s, err := SchemaOf(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.(Appender).AppendEncode(nil, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var safe, aliased blob
+	if _, err = s.(BytesDecoder).DecodeBytes(data, &safe); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = UnsafeDecodeBytes(s, data, &aliased); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(safe, value) || !reflect.DeepEqual(aliased, value) {
+		t.Fatalf("unexpected values %+v and %+v", safe, aliased)
+	}
+
+	// aliased byte slices refer to data
+	i := bytes.Index(data, []byte("data"))
+	data[i] = 'D'
+	if string(aliased.Data) != "Data" || string(safe.Data) != "data" {
+		t.Errorf("unexpected byte slices %q and %q", aliased.Data, safe.Data)
+	}
+}
+
+// TestVarBytesCompatibility checks that byte slices written as arrays of
+// integers by older versions can be read as binary strings
+func TestVarBytesCompatibility(t *testing.T) {
+
+	writer := &VarArraySchema{Element: &VarIntSchema{}}
+	reader := &VarBytesSchema{}
+	if list := CheckCompatibility(writer, reader); len(list) > 0 {
+		t.Fatalf("unexpected incompatibilities: %v", list)
+	}
+	res, err := NewResolver(writer, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	value := []byte{1, 2, 200}
+	if err := writer.Encode(&buf, value); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := res.Resolve(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, value) {
+		t.Errorf("expected %v; got %v", value, decoded)
+	}
+
+	list := CheckCompatibility(reader, &VarStringSchema{})
+	if len(list) != 1 || !strings.Contains(list[0].Reason, "weak decoding") {
+		t.Errorf("unexpected incompatibilities: %v", list)
+	}
+}
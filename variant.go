@@ -0,0 +1,168 @@
+package schemer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// VariantSchema is a Schema for dynamically-typed values. Each value is
+// encoded as the binary schema of the value followed by the value itself.
+// The schemas of values are built and decoded using the Registry that created
+// the VariantSchema, or DefaultRegistry if it was not created by a Registry.
+// Large schemas are written as a reference if the Registry has a
+// SchemaRegistry (see Registry.SetSchemaRegistry).
+type VariantSchema struct {
+	SchemaOptions
+
+	reg *Registry // nil means DefaultRegistry
+}
+
+// registry returns the Registry used for the schemas of values
+func (s *VariantSchema) registry() *Registry {
+	if s.reg == nil {
+		return DefaultRegistry
+	}
+	return s.reg
+}
+
+func (s *VariantSchema) GoType() reflect.Type {
+	// Note: interface types can hold nil values, so there is no need to
+	// wrap the type in a pointer when the schema is nullable
+	var t interface{}
+	return reflect.TypeOf(&t).Elem()
+}
+
+func (s *VariantSchema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"type":     "variant",
+		"nullable": s.Nullable(),
+	})
+}
+
+// Bytes encodes the schema in a portable binary format
+func (s *VariantSchema) MarshalSchemer() ([]byte, error) {
+
+	// variant schemas are 1 byte long
+	var schema []byte = []byte{VariantByte}
+
+	// The most signifiant bit indicates whether or not the type is nullable
+	if s.Nullable() {
+		schema[0] |= NullMask
+	}
+
+	return schema, nil
+}
+
+// Encode uses the schema to write the encoded value of i to the output stream
+func (s *VariantSchema) Encode(w io.Writer, i interface{}) error {
+	return s.EncodeValue(w, reflect.ValueOf(i))
+}
+
+// EncodeValue uses the schema to write the encoded value of v to the output
+// stream. The schema of the value is determined using the SchemaOfType method
+// of the schema's Registry and is written before the value itself.
+func (s *VariantSchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
+
+// AppendEncode appends the encoded value of i to dst and returns the extended
+// slice
+func (s *VariantSchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
+
+// AppendEncodeValue appends the encoded value of v, preceded by its schema, to
+// dst and returns the extended slice
+func (s *VariantSchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
+
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
+	if err != nil || done {
+		return dst, err
+	}
+
+	valueSchema, err := s.registry().SchemaOfType(v.Type())
+	if err != nil {
+		return dst, fmt.Errorf("variant value: %w", err)
+	}
+
+	m, ok := valueSchema.(Marshaler)
+	if !ok {
+		return dst, fmt.Errorf("variant value schema does not implement MarshalSchemer")
+	}
+	dst, err = s.registry().appendSchemaRef(dst, m)
+	if err != nil {
+		return dst, err
+	}
+	return appendEncodeValue(valueSchema, dst, v)
+}
+
+// Decode uses the schema to read the next encoded value from the input stream and store it in i
+func (s *VariantSchema) Decode(r io.Reader, i interface{}) error {
+	if i == nil {
+		return fmt.Errorf("cannot decode to nil destination")
+	}
+	return s.DecodeValue(r, reflect.ValueOf(i))
+}
+
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *VariantSchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
+// DecodeValue uses the schema to read the next encoded value from the input
+// stream and store it in v. If v is an interface, it is set to a value of the
+// Go type of the written schema; otherwise, the value is decoded into v using
+// the usual compatibility rules.
+func (s *VariantSchema) DecodeValue(r io.Reader, v reflect.Value) error {
+
+	v = resetInterface(v)
+
+	done, err := PreDecode(r, &v, s.Nullable())
+	if err != nil || done {
+		return err
+	}
+
+	valueSchema, err := s.registry().DecodeSchema(r)
+	if err != nil {
+		return fmt.Errorf("variant schema: %w", err)
+	}
+
+	if v.Kind() != reflect.Interface {
+		return valueSchema.DecodeValue(r, v)
+	}
+
+	// Ensure v is settable
+	if !v.CanSet() {
+		return fmt.Errorf("decode destination is not settable")
+	}
+
+	decoded := reflect.New(valueSchema.GoType())
+	err = valueSchema.DecodeValue(r, decoded)
+	if err != nil {
+		return err
+	}
+
+	if !decoded.Elem().Type().AssignableTo(v.Type()) {
+		return fmt.Errorf("decoded variant of type %v is not assignable to %v",
+			decoded.Elem().Type(), v.Type())
+	}
+	v.Set(decoded.Elem())
+
+	return nil
+}
+
+// resetInterface removes exactly one level of pointer indirection from v (if v
+// is not settable) and clears the interface value it refers to, if any. This
+// prevents PreDecode from dereferencing the value currently stored in an
+// interface destination, which is usually not settable.
+func resetInterface(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Ptr && !v.CanSet() && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Interface && v.CanSet() && !v.IsNil() {
+		v.Set(reflect.Zero(v.Type()))
+	}
+	return v
+}
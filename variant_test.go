@@ -0,0 +1,269 @@
+package schemer
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type variantStruct struct {
+	Name  string
+	Value interface{}
+}
+
+type variantStringer struct {
+	S string
+}
+
+func (v variantStringer) String() string {
+	return v.S
+}
+
+func TestVariantSchemaOf(t *testing.T) {
+
+	s, err := SchemaOf(variantStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixedObjectSchema, ok := s.(*FixedObjectSchema)
+	if !ok {
+		t.Fatal("fixedObjectSchema assertion failed")
+	}
+
+	variantSchema, ok := fixedObjectSchema.Fields[1].Schema.(*VariantSchema)
+	if !ok {
+		t.Fatal("expected VariantSchema for interface field")
+	}
+	if !variantSchema.Nullable() {
+		t.Error("expected interface field to be nullable")
+	}
+}
+
+func TestVariantSchemaRoundTrip(t *testing.T) {
+
+	s := &VariantSchema{}
+	s.SetNullable(true)
+
+	// binary
+	b, err := s.MarshalSchemer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != VariantByte|NullMask {
+		t.Fatalf("unexpected binary schema %x", b)
+	}
+	tmp, err := DecodeSchema(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedSchema, ok := tmp.(*VariantSchema)
+	if !ok || !decodedSchema.Nullable() {
+		t.Fatal("unexpected values when decoding binary VariantSchema")
+	}
+
+	// JSON
+	b, err = s.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp, err = DecodeSchemaJSON(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedSchema, ok = tmp.(*VariantSchema)
+	if !ok || !decodedSchema.Nullable() {
+		t.Fatal("unexpected values when decoding JSON VariantSchema")
+	}
+}
+
+func TestDecodeVariant1(t *testing.T) {
+
+	s, err := SchemaOf(variantStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []interface{}{
+		42,
+		"hello, world",
+		3.14,
+		[]string{"a", "b"},
+		map[string]bool{"x": true},
+		nil,
+	}
+
+	for _, value := range values {
+		fmt.Printf("variant %#v\n", value)
+
+		var buf bytes.Buffer
+		err = s.Encode(&buf, variantStruct{Name: "test", Value: value})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded variantStruct
+		err = s.Decode(bytes.NewReader(buf.Bytes()), &decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if decoded.Name != "test" {
+			t.Error("unexpected name decode")
+		}
+		if !reflect.DeepEqual(decoded.Value, value) {
+			t.Errorf("expected %#v; got %#v", value, decoded.Value)
+		}
+	}
+}
+
+// make sure a variant can be decoded to a concrete destination and that
+// existing interface values are replaced
+func TestDecodeVariant2(t *testing.T) {
+
+	s := &VariantSchema{}
+
+	var buf bytes.Buffer
+	err := s.Encode(&buf, int64(-1234))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var i int32
+	err = s.Decode(bytes.NewReader(buf.Bytes()), &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i != -1234 {
+		t.Errorf("expected -1234; got %d", i)
+	}
+
+	var iface interface{} = "existing value"
+	err = s.Decode(bytes.NewReader(buf.Bytes()), &iface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iface != -1234 {
+		t.Errorf("expected -1234; got %#v", iface)
+	}
+}
+
+// corner cases:
+// make sure nil values cannot be encoded by non-nullable variants, and make
+// sure values are only decoded to interfaces they implement
+func TestDecodeVariant3(t *testing.T) {
+
+	s := &VariantSchema{}
+
+	var buf bytes.Buffer
+	err := s.Encode(&buf, nil)
+	if err == nil {
+		t.Error("expected error encoding nil value to non-nullable variant")
+	}
+
+	buf.Reset()
+	err = s.Encode(&buf, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stringer fmt.Stringer
+	err = s.Decode(bytes.NewReader(buf.Bytes()), &stringer)
+	if err == nil {
+		t.Error("expected error decoding int to fmt.Stringer")
+	}
+
+	buf.Reset()
+	err = s.Encode(&buf, variantStringer{S: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded interface{}
+	err = s.Decode(bytes.NewReader(buf.Bytes()), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.ValueOf(decoded).FieldByName("S").String() != "abc" {
+		t.Errorf("unexpected struct variant decode: %#v", decoded)
+	}
+}
+
+// TestVariantSchemaReference checks that large schemas of variants and schema
+// values are written as references to a SchemaRegistry
+func TestVariantSchemaReference(t *testing.T) {
+
+	reg := NewRegistry()
+	sr := NewMemorySchemaRegistry()
+	reg.SetSchemaRegistry(sr)
+
+	type dynamic struct {
+		V interface{}
+		S Schema
+	}
+	s, err := reg.SchemaOf(dynamic{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	large := decodeBytesStruct{ID: 7, Name: "name", Code: "abcd"}
+	largeSchema, err := SchemaOf(large)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []dynamic{
+		{V: true, S: &BoolSchema{}},
+		{V: large, S: largeSchema},
+	} {
+		var buf bytes.Buffer
+		if err = s.Encode(&buf, value); err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded dynamic
+		if err = s.Decode(bytes.NewReader(buf.Bytes()), &decoded); err != nil {
+			t.Fatal(err)
+		}
+		expected, err := CanonicalForm(value.S)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := CanonicalForm(decoded.S); err != nil || !bytes.Equal(got, expected) {
+			t.Errorf("expected schema %s; got %s", expected, got)
+		}
+		if value.V == true && decoded.V != true || decoded.V == nil {
+			t.Errorf("unexpected value %#v", decoded.V)
+		}
+
+		// references cannot be resolved by a Registry without the SchemaRegistry
+		b, err := s.(Marshaler).MarshalSchemer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := NewRegistry().DecodeSchema(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = other.Decode(bytes.NewReader(buf.Bytes()), &decoded)
+		if (err != nil) != (value.S == largeSchema) {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+
+	// the large schema was registered once
+	fp, err := Fingerprint64(largeSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _, err := sr.LookupFingerprint(fp); err != nil || id != 1 {
+		t.Errorf("expected schema 1; got %d, %v", id, err)
+	}
+
+	var ref UUID
+	copy(ref[:], schemaRefPrefix[:])
+	err = reg.Register(durationSchemaGenerator{id: ref})
+	if err == nil {
+		t.Error("expected error registering reserved UUID")
+	}
+}
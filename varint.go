@@ -66,58 +66,45 @@ func (s *VarIntSchema) Encode(w io.Writer, i interface{}) error {
 
 // EncodeValue uses the schema to write the encoded value of v to the output stream
 func (s *VarIntSchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
+
+// AppendEncode appends the encoded value of i to dst and returns the extended slice
+func (s *VarIntSchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
+
+// AppendEncodeValue appends the encoded value of v to dst and returns the extended slice
+func (s *VarIntSchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
 
-	done, err := PreEncode(w, &v, s.Nullable())
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
 	if err != nil || done {
-		return err
+		return dst, err
 	}
 
-	t := v.Type()
-	k := t.Kind()
-
-	switch k {
-	case reflect.Int:
-		fallthrough
-	case reflect.Int8:
-		fallthrough
-	case reflect.Int16:
-		fallthrough
-	case reflect.Int32:
-		fallthrough
-	case reflect.Int64:
+	switch k := v.Kind(); {
+	case isIntKind(k):
 		intVal := v.Int()
-		// Write value
 		if s.Signed {
 			uintVal := uint64(intVal) << 1
 			if intVal < 0 {
 				uintVal = ^uintVal
 			}
-			return WriteUvarint(w, uintVal)
-		} else {
-			if intVal < 0 {
-				return fmt.Errorf("cannot encode negative integer")
-			}
-			uintVal := uint64(intVal)
-			return WriteUvarint(w, uintVal)
+			return appendUvarint(dst, uintVal), nil
+		}
+		if intVal < 0 {
+			return dst, fmt.Errorf("cannot encode negative integer")
 		}
-	case reflect.Uint:
-		fallthrough
-	case reflect.Uint8:
-		fallthrough
-	case reflect.Uint16:
-		fallthrough
-	case reflect.Uint32:
-		fallthrough
-	case reflect.Uint64:
+		return appendUvarint(dst, uint64(intVal)), nil
+	case isUintKind(k):
 		uintVal := v.Uint()
 		if s.Signed {
-			return WriteUvarint(w, uintVal<<1)
-		} else {
-			return WriteUvarint(w, uintVal)
+			return appendUvarint(dst, uintVal<<1), nil
 		}
+		return appendUvarint(dst, uintVal), nil
 	}
 
-	return nil
+	return dst, nil
 }
 
 // Decode uses the schema to read the next encoded value from the input stream and store it in i
@@ -128,6 +115,11 @@ func (s *VarIntSchema) Decode(r io.Reader, i interface{}) error {
 	return s.DecodeValue(r, reflect.ValueOf(i))
 }
 
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *VarIntSchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
 // DecodeValue uses the schema to read the next encoded value from the input stream and store it in v
 func (s *VarIntSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 
@@ -113,38 +113,41 @@ func (s *VarObjectSchema) Encode(w io.Writer, i interface{}) error {
 
 // EncodeValue uses the schema to write the encoded value of v to the output streamm
 func (s *VarObjectSchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
 
-	done, err := PreEncode(w, &v, s.Nullable())
-	if err != nil || done {
-		return err
-	}
+// AppendEncode appends the encoded value of i to dst and returns the extended slice
+func (s *VarObjectSchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
 
-	t := v.Type()
-	k := t.Kind()
+// AppendEncodeValue appends the encoded value of v to dst and returns the extended slice
+func (s *VarObjectSchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
 
-	if k != reflect.Map {
-		return fmt.Errorf("varObjectSchema can only encode maps")
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
+	if err != nil || done {
+		return dst, err
 	}
 
-	err = WriteUvarint(w, uint64(v.Len()))
-	if err != nil {
-		return errors.New("cannot encode var string length as var int")
+	if v.Kind() != reflect.Map {
+		return dst, fmt.Errorf("varObjectSchema can only encode maps")
 	}
 
-	for _, mapKey := range v.MapKeys() {
-		mapValue := v.MapIndex(mapKey)
+	dst = appendUvarint(dst, uint64(v.Len()))
 
-		err := s.Key.Encode(w, mapKey.Interface()) // encode key
+	iter := v.MapRange()
+	for iter.Next() {
+		dst, err = appendEncodeValue(s.Key, dst, iter.Key()) // encode key
 		if err != nil {
-			return err
+			return dst, err
 		}
-		err = s.Value.Encode(w, mapValue.Interface()) // encode value
+		dst, err = appendEncodeValue(s.Value, dst, iter.Value()) // encode value
 		if err != nil {
-			return err
+			return dst, err
 		}
 	}
 
-	return nil
+	return dst, nil
 }
 
 // Decode uses the schema to read the next encoded value from the input stream and store it in i
@@ -155,6 +158,11 @@ func (s *VarObjectSchema) Decode(r io.Reader, i interface{}) error {
 	return s.DecodeValue(r, reflect.ValueOf(i))
 }
 
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *VarObjectSchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
 // DecodeValue uses the schema to read the next encoded value from the input stream and store it in v
 func (s *VarObjectSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 
@@ -186,6 +194,10 @@ func (s *VarObjectSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 	if err != nil {
 		return err
 	}
+	entrySize := minEncodedSize(s.Key) + minEncodedSize(s.Value)
+	if _, err := checkLen(r, expectedNumEntries, entrySize); err != nil {
+		return err
+	}
 
 	if v.IsNil() {
 		if !v.CanSet() {
@@ -198,7 +210,7 @@ func (s *VarObjectSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 	// right now by default, we will just keep their entries
 	// but we have to decide if this behavior is OK
 
-	for i := 0; i < int(expectedNumEntries); i++ {
+	for i := uint64(0); i < expectedNumEntries; i++ {
 
 		key := reflect.New(t.Key())
 		val := reflect.New(t.Elem())
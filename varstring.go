@@ -2,7 +2,6 @@ package schemer
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"reflect"
@@ -53,41 +52,29 @@ func (s *VarStringSchema) Encode(w io.Writer, i interface{}) error {
 
 // EncodeValue uses the schema to write the encoded value of v to the output stream
 func (s *VarStringSchema) EncodeValue(w io.Writer, v reflect.Value) error {
+	return encodeValue(w, s, v)
+}
 
-	done, err := PreEncode(w, &v, s.Nullable())
-	if err != nil || done {
-		return err
-	}
-
-	t := v.Type()
-	k := t.Kind()
-
-	if k == reflect.Interface {
-		v.Set(reflect.New(s.GoType()))
-
-		v = v.Elem().Elem()
-		t = v.Type()
-		k = t.Kind()
-	}
-
-	if k != reflect.String {
-		return fmt.Errorf("StringSchema only supports encoding string values")
-	}
+// AppendEncode appends the encoded value of i to dst and returns the extended slice
+func (s *VarStringSchema) AppendEncode(dst []byte, i interface{}) ([]byte, error) {
+	return s.AppendEncodeValue(dst, reflect.ValueOf(i))
+}
 
-	var stringToEncode string = v.String()
-	var stringLen int = len(stringToEncode)
+// AppendEncodeValue appends the encoded value of v to dst and returns the extended slice
+func (s *VarStringSchema) AppendEncodeValue(dst []byte, v reflect.Value) ([]byte, error) {
 
-	err = WriteUvarint(w, uint64(stringLen))
-	if err != nil {
-		return errors.New("cannot encode var string length as var int")
+	dst, done, err := PreAppend(dst, &v, s.Nullable())
+	if err != nil || done {
+		return dst, err
 	}
 
-	n, err := w.Write([]byte(stringToEncode))
-	if err == nil && n != stringLen {
-		return errors.New("unexpected number of bytes written")
+	if v.Kind() != reflect.String {
+		return dst, fmt.Errorf("StringSchema only supports encoding string values")
 	}
 
-	return nil
+	stringToEncode := v.String()
+	dst = appendUvarint(dst, uint64(len(stringToEncode)))
+	return append(dst, stringToEncode...), nil
 }
 
 // Decode uses the schema to read the next encoded value from the input stream and store it in i
@@ -98,6 +85,11 @@ func (s *VarStringSchema) Decode(r io.Reader, i interface{}) error {
 	return s.DecodeValue(r, reflect.ValueOf(i))
 }
 
+// DecodeBytes decodes the encoded value at the start of data, stores it in i, and returns the number of bytes read
+func (s *VarStringSchema) DecodeBytes(data []byte, i interface{}) (int, error) {
+	return decodeBytesInterface(s, data, i)
+}
+
 // DecodeValue uses the schema to read the next encoded value from the input stream and store it in v
 func (s *VarStringSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 
@@ -122,15 +114,11 @@ func (s *VarStringSchema) DecodeValue(r io.Reader, v reflect.Value) error {
 		return err
 	}
 
-	buf := make([]byte, int(expectedLen))
-	_, err = io.ReadAtLeast(r, buf, int(expectedLen))
+	decodedString, err := readString(r, expectedLen)
 	if err != nil {
 		return err
 	}
 
-	// when we return as a string, we will return it with the padding intact
-	decodedString := string(buf)
-
 	// but for conversions, having a trimmed up string will make things easier
 	trimString := strings.Trim(decodedString, " ")
 